@@ -0,0 +1,481 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcy326/tivor/internal/backend"
+)
+
+// ErrNotCached is returned (wrapped) in offline mode when a vars file is not in the cache.
+var ErrNotCached = errors.New("not in the cache")
+
+// DirEnv is the environment variable overriding the cache directory
+const DirEnv = "TIVOR_CACHE_DIR"
+
+// Entry describes a vars file stored in the cache
+type Entry struct {
+	// Type is the backend type and Backend identifies its configuration
+	Type    string `json:"type"`
+	Backend string `json:"backend"`
+	// Location describes where the backend reads from, without credentials
+	Location string `json:"location,omitempty"`
+	Path     string `json:"path"`
+	// Version is the version reported by the backend, if any
+	Version string `json:"version,omitempty"`
+	// Digest is the SHA-256 checksum of the content, which is stored by digest
+	Digest    string    `json:"digest,omitempty"`
+	Size      int64     `json:"size"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// Missing records that the file did not exist, so optional files resolve offline
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Cache is a content-addressed store of vars files fetched from remote backends.
+// Contents are stored under objects/ by digest and described by entries under index/.
+// A copy of the most recent entry of each path is kept under latest/ for offline reads.
+type Cache struct {
+	dir string
+}
+
+// DefaultDir returns the cache directory: $TIVOR_CACHE_DIR, or tivor in the user cache directory
+// (~/.cache/tivor on Linux)
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return filepath.Abs(dir)
+	}
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine cache directory: %w", err)
+	}
+	return filepath.Join(userCacheDir, "tivor"), nil
+}
+
+// New creates a cache stored in dir
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns the most recently fetched entry of a path and its content
+func (c *Cache) Get(typ, id, p string) (*Entry, []byte, error) {
+	data, err := os.ReadFile(c.latestPath(typ, id, p))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotCached, p)
+	}
+	var latest Entry
+	if err := json.Unmarshal(data, &latest); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s (invalid entry: %v)", ErrNotCached, p, err)
+	}
+	if latest.Missing {
+		return &latest, nil, nil
+	}
+
+	content, err := os.ReadFile(c.objectPath(latest.Digest))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s (content missing: %v)", ErrNotCached, p, err)
+	}
+	return &latest, content, nil
+}
+
+// Lookup returns the entry of a path at a version and its content
+func (c *Cache) Lookup(typ, id, p, version string) (*Entry, []byte, error) {
+	data, err := os.ReadFile(c.indexPath(Entry{Type: typ, Backend: id, Path: p, Version: version}))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s at version %s", ErrNotCached, p, version)
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Missing {
+		return nil, nil, fmt.Errorf("%w: %s at version %s", ErrNotCached, p, version)
+	}
+
+	content, err := os.ReadFile(c.objectPath(entry.Digest))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s (content missing: %v)", ErrNotCached, p, err)
+	}
+	return &entry, content, nil
+}
+
+// Put stores content and its entry. The digest, size and fetch time are filled in.
+func (c *Cache) Put(entry Entry, content []byte) error {
+	if !entry.Missing {
+		sum := sha256.Sum256(content)
+		entry.Digest = hex.EncodeToString(sum[:])
+		entry.Size = int64(len(content))
+
+		if _, err := os.Stat(c.objectPath(entry.Digest)); err != nil {
+			if err := writeFileAtomic(c.objectPath(entry.Digest), content); err != nil {
+				return fmt.Errorf("failed to store cached content: %w", err)
+			}
+		}
+	}
+	entry.FetchedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(c.indexPath(entry), data); err != nil {
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	if err := writeFileAtomic(c.latestPath(entry.Type, entry.Backend, entry.Path), data); err != nil {
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	return nil
+}
+
+// List returns every cache entry, sorted by type, backend, path and fetch time
+func (c *Cache) List() ([]Entry, error) {
+	files, err := os.ReadDir(filepath.Join(c.dir, "index"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache index: %w", err)
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.dir, "index", file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cache entry: %w", err)
+		}
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			slog.Warn("Ignoring invalid cache entry", "file", file.Name(), "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Backend != b.Backend {
+			return a.Backend < b.Backend
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.FetchedAt.Before(b.FetchedAt)
+	})
+	return entries, nil
+}
+
+// Prune removes the entries fetched before the cutoff and the contents no entry refers to.
+// It returns the number of removed entries and contents.
+func (c *Cache) Prune(cutoff time.Time) (int, int, error) {
+	entries, err := c.List()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removedEntries := 0
+	referenced := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.FetchedAt.Before(cutoff) {
+			if err := os.Remove(c.indexPath(entry)); err != nil && !os.IsNotExist(err) {
+				return removedEntries, 0, fmt.Errorf("failed to remove cache entry: %w", err)
+			}
+			removedEntries++
+			continue
+		}
+		referenced[entry.Digest] = true
+	}
+
+	// Latest entries are copies of index entries, fetched at the same time
+	latest, err := os.ReadDir(filepath.Join(c.dir, "latest"))
+	if err != nil && !os.IsNotExist(err) {
+		return removedEntries, 0, fmt.Errorf("failed to read cache index: %w", err)
+	}
+	for _, file := range latest {
+		var entry Entry
+		data, err := os.ReadFile(filepath.Join(c.dir, "latest", file.Name()))
+		if err == nil {
+			err = json.Unmarshal(data, &entry)
+		}
+		if err == nil && !entry.FetchedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, "latest", file.Name())); err != nil && !os.IsNotExist(err) {
+			return removedEntries, 0, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+	}
+
+	objects, err := os.ReadDir(filepath.Join(c.dir, "objects"))
+	if err != nil && !os.IsNotExist(err) {
+		return removedEntries, 0, fmt.Errorf("failed to read cache contents: %w", err)
+	}
+	removedObjects := 0
+	for _, object := range objects {
+		if referenced[object.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, "objects", object.Name())); err != nil && !os.IsNotExist(err) {
+			return removedEntries, removedObjects, fmt.Errorf("failed to remove cached content: %w", err)
+		}
+		removedObjects++
+	}
+
+	return removedEntries, removedObjects, nil
+}
+
+// cacheDirs are the subdirectories of the cache directory that tivor creates:
+// objects, entries and latest entries
+var cacheDirs = []string{"objects", "index", "latest"}
+
+// Clear removes what tivor stored in the cache directory. Other files are kept, in case
+// the directory is shared, and the directory itself is removed only if nothing else is left.
+func (c *Cache) Clear() error {
+	for _, name := range cacheDirs {
+		if err := os.RemoveAll(filepath.Join(c.dir, name)); err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+	}
+	// Fails if the directory holds other files
+	if err := os.Remove(c.dir); err != nil && !os.IsNotExist(err) {
+		slog.Debug("Kept cache directory", "dir", c.dir, "error", err)
+	}
+	return nil
+}
+
+// Wrap returns a backend reading through the cache. Every file fetched from b is stored;
+// in offline mode, files are served only from the cache. Backends producing variables
+// directly, such as secret stores, are never cached. id identifies the backend configuration
+// and location describes it for display; neither may contain credentials.
+func (c *Cache) Wrap(b backend.Backend, typ, id, location string, offline bool) backend.Backend {
+	if _, ok := b.(backend.VariablesGetter); ok {
+		if offline {
+			return &unavailableBackend{typ: typ}
+		}
+		return b
+	}
+	return &cachedBackend{cache: c, inner: b, typ: typ, id: id, location: location, offline: offline}
+}
+
+// cachedBackend reads a backend through the cache
+type cachedBackend struct {
+	cache    *Cache
+	inner    backend.Backend
+	typ      string
+	id       string
+	location string
+	offline  bool
+}
+
+// GetVarsFile retrieves a variable file from the backend, or from the cache in offline mode
+func (b *cachedBackend) GetVarsFile(ctx context.Context, p string) ([]byte, error) {
+	content, _, err := b.GetVersionedVarsFile(ctx, p)
+	return content, err
+}
+
+// GetVersionedVarsFile retrieves a variable file like GetVarsFile, along with the version
+// the backend reported for that content, or the version it was cached with
+func (b *cachedBackend) GetVersionedVarsFile(ctx context.Context, p string) ([]byte, string, error) {
+	if b.offline {
+		entry, content, err := b.cache.Get(b.typ, b.id, p)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s backend file %s cannot be read offline: %w", b.typ, p, err)
+		}
+		if entry.Missing {
+			return nil, "", fmt.Errorf("%w: %s (cached)", backend.ErrNotFound, p)
+		}
+		slog.Debug("Using cached vars file", "type", b.typ, "path", p, "fetched_at", entry.FetchedAt)
+		return content, entry.Version, nil
+	}
+
+	// Skip the download if the backend reports a version that is already cached
+	version := b.version(ctx, p)
+	if version != "" {
+		if entry, content, err := b.cache.Lookup(b.typ, b.id, p, version); err == nil {
+			slog.Debug("Vars file unchanged, using cached copy", "type", b.typ, "path", p, "version", version)
+			// Storing the entry again marks it as recently used for prune
+			if putErr := b.cache.Put(*entry, content); putErr != nil {
+				slog.Warn("Failed to cache vars file", "type", b.typ, "path", p, "error", putErr)
+			}
+			return content, version, nil
+		}
+	}
+
+	// Prefer the version reported with the content, in case the file changed since Stat
+	content, fetchedVersion, err := b.fetch(ctx, p)
+	if fetchedVersion != "" {
+		version = fetchedVersion
+	}
+	entry := Entry{Type: b.typ, Backend: b.id, Location: b.location, Path: p, Version: version}
+	switch {
+	case errors.Is(err, backend.ErrNotFound):
+		entry.Missing = true
+		entry.Version = ""
+	case err != nil:
+		return nil, "", err
+	}
+
+	if putErr := b.cache.Put(entry, content); putErr != nil {
+		slog.Warn("Failed to cache vars file", "type", b.typ, "path", p, "error", putErr)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return content, version, nil
+}
+
+// Glob expands a pattern with the backend, or against the cached paths in offline mode
+func (b *cachedBackend) Glob(ctx context.Context, pattern string) ([]string, error) {
+	if !b.offline {
+		return backend.Glob(ctx, b.inner, pattern)
+	}
+
+	entries, err := b.cache.List()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var paths []string
+	for _, entry := range entries {
+		if entry.Type != b.typ || entry.Backend != b.id || entry.Missing || seen[entry.Path] {
+			continue
+		}
+		if matched, _ := path.Match(pattern, entry.Path); matched {
+			seen[entry.Path] = true
+			paths = append(paths, entry.Path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Stat describes a file with the backend, or from its cache entry in offline mode
+func (b *cachedBackend) Stat(ctx context.Context, p string) (*backend.FileInfo, error) {
+	if !b.offline {
+		stater, ok := b.inner.(backend.Stater)
+		if !ok {
+			return nil, fmt.Errorf("%s backend cannot describe files", b.typ)
+		}
+		return stater.Stat(ctx, p)
+	}
+
+	entry, _, err := b.cache.Get(b.typ, b.id, p)
+	if err != nil {
+		return nil, fmt.Errorf("%s backend file %s cannot be described offline: %w", b.typ, p, err)
+	}
+	if entry.Missing {
+		return nil, fmt.Errorf("%w: %s (cached)", backend.ErrNotFound, p)
+	}
+	return &backend.FileInfo{Path: p, Size: entry.Size, Version: entry.Version}, nil
+}
+
+// fetch downloads a file and its version. Backends that can revalidate the cached copy
+// send the file only if it changed since it was cached.
+func (b *cachedBackend) fetch(ctx context.Context, p string) ([]byte, string, error) {
+	getter, ok := b.inner.(backend.ConditionalGetter)
+	if !ok {
+		return backend.GetVersioned(ctx, b.inner, p)
+	}
+	entry, cached, err := b.cache.Get(b.typ, b.id, p)
+	if err != nil || entry.Missing || entry.Version == "" {
+		return backend.GetVersioned(ctx, b.inner, p)
+	}
+
+	content, version, unchanged, err := getter.GetVarsFileIfChanged(ctx, p, entry.Version)
+	if err != nil {
+		return nil, "", err
+	}
+	if unchanged {
+		slog.Debug("Vars file unchanged, using cached copy", "type", b.typ, "path", p, "version", entry.Version)
+		return cached, entry.Version, nil
+	}
+	return content, version, nil
+}
+
+// version returns the version of a file reported by the backend, its ETag if it has no
+// version, or "" if the backend cannot describe files. Backends revalidating the cached
+// copy are not asked, as that takes as many requests as describing the file.
+func (b *cachedBackend) version(ctx context.Context, p string) string {
+	if _, ok := b.inner.(backend.ConditionalGetter); ok {
+		return ""
+	}
+	stater, ok := b.inner.(backend.Stater)
+	if !ok {
+		return ""
+	}
+	info, err := stater.Stat(ctx, p)
+	if err != nil {
+		// GetVarsFile reports the error, or ErrNotFound for missing files
+		slog.Debug("Failed to stat vars file", "type", b.typ, "path", p, "error", err)
+		return ""
+	}
+	if info.Version != "" {
+		return info.Version
+	}
+	return info.ETag
+}
+
+// unavailableBackend stands in for backends that cannot be used offline
+type unavailableBackend struct {
+	typ string
+}
+
+// GetVarsFile always fails
+func (b *unavailableBackend) GetVarsFile(ctx context.Context, p string) ([]byte, error) {
+	return nil, fmt.Errorf("%s backend is not available offline: its values are never cached", b.typ)
+}
+
+// objectPath returns the path of content stored by digest
+func (c *Cache) objectPath(digest string) string {
+	return filepath.Join(c.dir, "objects", digest)
+}
+
+// indexPath returns the path of the entry of a backend, path and version
+func (c *Cache) indexPath(entry Entry) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{entry.Type, entry.Backend, entry.Path, entry.Version}, "\x00")))
+	return filepath.Join(c.dir, "index", hex.EncodeToString(sum[:])+".json")
+}
+
+// latestPath returns the path of the most recent entry of a backend and path
+func (c *Cache) latestPath(typ, id, p string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{typ, id, p}, "\x00")))
+	return filepath.Join(c.dir, "latest", hex.EncodeToString(sum[:])+".json")
+}
+
+// writeFileAtomic writes a private file through a temporary file so readers never see partial content
+func writeFileAtomic(p string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marcy326/tivor/internal/backend"
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+// fakeBackend serves files from a map and counts downloads
+type fakeBackend struct {
+	files     map[string]string
+	versions  map[string]string
+	downloads int
+}
+
+func (f *fakeBackend) GetVarsFile(ctx context.Context, p string) ([]byte, error) {
+	content, ok := f.files[p]
+	if !ok {
+		return nil, backend.ErrNotFound
+	}
+	f.downloads++
+	return []byte(content), nil
+}
+
+func (f *fakeBackend) Stat(ctx context.Context, p string) (*backend.FileInfo, error) {
+	if _, ok := f.files[p]; !ok {
+		return nil, backend.ErrNotFound
+	}
+	return &backend.FileInfo{Path: p, Version: f.versions[p]}, nil
+}
+
+// revalidatingBackend answers conditional requests, like an HTTP server with ETags
+type revalidatingBackend struct {
+	fakeBackend
+	revalidations int
+}
+
+func (r *revalidatingBackend) GetVarsFileIfChanged(ctx context.Context, p, version string) ([]byte, string, bool, error) {
+	r.revalidations++
+	if version == r.versions[p] {
+		return nil, version, true, nil
+	}
+	content, err := r.GetVarsFile(ctx, p)
+	return content, r.versions[p], false, err
+}
+
+func (r *revalidatingBackend) GetVersionedVarsFile(ctx context.Context, p string) ([]byte, string, error) {
+	content, err := r.GetVarsFile(ctx, p)
+	return content, r.versions[p], err
+}
+
+// secretBackend produces variables directly, like a secret store
+type secretBackend struct{ fakeBackend }
+
+func (s *secretBackend) GetVariables(ctx context.Context, p string) ([]tfvars.Variable, error) {
+	return nil, nil
+}
+
+func TestGetAndLookup(t *testing.T) {
+	c := New(t.TempDir())
+	if err := c.Put(Entry{Type: "git", Backend: "id", Path: "a.tfvars", Version: "v1"}, []byte("a = 1\n")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := c.Put(Entry{Type: "git", Backend: "id", Path: "a.tfvars", Version: "v2"}, []byte("a = 2\n")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := c.Put(Entry{Type: "git", Backend: "id", Path: "gone.tfvars", Missing: true}, nil); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		get         func() (*Entry, []byte, error)
+		want        string
+		wantMissing bool
+		wantErr     bool
+	}{
+		{name: "latest", get: func() (*Entry, []byte, error) { return c.Get("git", "id", "a.tfvars") }, want: "a = 2\n"},
+		{name: "older version", get: func() (*Entry, []byte, error) { return c.Lookup("git", "id", "a.tfvars", "v1") }, want: "a = 1\n"},
+		{name: "missing file", get: func() (*Entry, []byte, error) { return c.Get("git", "id", "gone.tfvars") }, wantMissing: true},
+		{name: "unknown version", get: func() (*Entry, []byte, error) { return c.Lookup("git", "id", "a.tfvars", "v3") }, wantErr: true},
+		{name: "other backend", get: func() (*Entry, []byte, error) { return c.Get("git", "other", "a.tfvars") }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, content, err := tt.get()
+			if tt.wantErr {
+				if !errors.Is(err, ErrNotCached) {
+					t.Fatalf("error = %v, want ErrNotCached", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if entry.Missing != tt.wantMissing {
+				t.Errorf("Missing = %v, want %v", entry.Missing, tt.wantMissing)
+			}
+			if string(content) != tt.want {
+				t.Errorf("content = %q, want %q", content, tt.want)
+			}
+		})
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("List() returned %d entries, want 3", len(entries))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir)
+	if err := c.Put(Entry{Type: "http", Backend: "id", Path: "a.tfvars"}, []byte("a = 1\n")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	entries, objects, err := c.Prune(time.Now().Add(-time.Hour))
+	if err != nil || entries != 0 || objects != 0 {
+		t.Fatalf("Prune() of recent entries = %d, %d, %v, want nothing removed", entries, objects, err)
+	}
+
+	entries, objects, err = c.Prune(time.Now().Add(time.Hour))
+	if err != nil || entries != 1 || objects != 1 {
+		t.Fatalf("Prune() = %d, %d, %v, want 1 entry and 1 object removed", entries, objects, err)
+	}
+	if _, _, err := c.Get("http", "id", "a.tfvars"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("Get() after prune error = %v, want ErrNotCached", err)
+	}
+	for _, sub := range []string{"index", "latest", "objects"} {
+		files, _ := os.ReadDir(filepath.Join(dir, sub))
+		if len(files) != 0 {
+			t.Errorf("%s still has %d files after prune", sub, len(files))
+		}
+	}
+}
+
+func TestClear(t *testing.T) {
+	tests := []struct {
+		name      string
+		other     string
+		wantDir   bool
+		wantOther bool
+	}{
+		{name: "dedicated directory", wantDir: false},
+		// TIVOR_CACHE_DIR may point at a directory holding other files
+		{name: "shared directory", other: "notes.txt", wantDir: true, wantOther: true},
+		{name: "shared directory with subdirectories", other: "src/main.go", wantDir: true, wantOther: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "cache")
+			c := New(dir)
+			if err := c.Put(Entry{Type: "http", Backend: "id", Path: "a.tfvars"}, []byte("a = 1\n")); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if tt.other != "" {
+				other := filepath.Join(dir, tt.other)
+				os.MkdirAll(filepath.Dir(other), 0755)
+				if err := os.WriteFile(other, []byte("keep"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := c.Clear(); err != nil {
+				t.Fatalf("Clear() error = %v", err)
+			}
+
+			if _, err := os.Stat(dir); (err == nil) != tt.wantDir {
+				t.Errorf("cache directory exists = %v, want %v", err == nil, tt.wantDir)
+			}
+			if tt.other != "" {
+				if _, err := os.Stat(filepath.Join(dir, tt.other)); (err == nil) != tt.wantOther {
+					t.Errorf("%s exists = %v, want %v", tt.other, err == nil, tt.wantOther)
+				}
+			}
+			for _, sub := range []string{"index", "latest", "objects"} {
+				if _, err := os.Stat(filepath.Join(dir, sub)); err == nil {
+					t.Errorf("%s still exists after clear", sub)
+				}
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	c := New(t.TempDir())
+	inner := &fakeBackend{
+		files:    map[string]string{"vars/a.tfvars": "a = 1\n", "vars/b.tfvars": "b = 2\n"},
+		versions: map[string]string{"vars/a.tfvars": "v1", "vars/b.tfvars": "v1"},
+	}
+	ctx := context.Background()
+
+	online := c.Wrap(inner, "git", "id", "repo@main", false)
+	for i := 0; i < 2; i++ {
+		if _, err := online.GetVarsFile(ctx, "vars/a.tfvars"); err != nil {
+			t.Fatalf("GetVarsFile() error = %v", err)
+		}
+	}
+	if inner.downloads != 1 {
+		t.Errorf("downloaded %d times for an unchanged version, want 1", inner.downloads)
+	}
+	if _, err := online.GetVarsFile(ctx, "vars/missing.tfvars"); !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("GetVarsFile() of a missing file error = %v, want ErrNotFound", err)
+	}
+
+	inner.files["vars/a.tfvars"] = "a = 3\n"
+	inner.versions["vars/a.tfvars"] = "v2"
+	if content, err := online.GetVarsFile(ctx, "vars/a.tfvars"); err != nil || string(content) != "a = 3\n" {
+		t.Fatalf("GetVarsFile() after a change = %q, %v, want the new content", content, err)
+	}
+
+	entry, _, err := c.Get("git", "id", "vars/a.tfvars")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if entry.Location != "repo@main" || entry.Version != "v2" {
+		t.Errorf("entry = %+v, want location repo@main and version v2", entry)
+	}
+
+	offline := c.Wrap(inner, "git", "id", "repo@main", true)
+	tests := []struct {
+		name         string
+		path         string
+		want         string
+		wantNotFound bool
+		wantNotCache bool
+	}{
+		{name: "cached", path: "vars/a.tfvars", want: "a = 3\n"},
+		{name: "cached as missing", path: "vars/missing.tfvars", wantNotFound: true},
+		{name: "never fetched", path: "vars/b.tfvars", wantNotCache: true},
+	}
+	for _, tt := range tests {
+		t.Run("offline "+tt.name, func(t *testing.T) {
+			content, err := offline.GetVarsFile(ctx, tt.path)
+			switch {
+			case tt.wantNotFound:
+				if !errors.Is(err, backend.ErrNotFound) {
+					t.Fatalf("GetVarsFile() error = %v, want ErrNotFound", err)
+				}
+			case tt.wantNotCache:
+				if !errors.Is(err, ErrNotCached) {
+					t.Fatalf("GetVarsFile() error = %v, want ErrNotCached", err)
+				}
+			default:
+				if err != nil || string(content) != tt.want {
+					t.Fatalf("GetVarsFile() = %q, %v, want %q", content, err, tt.want)
+				}
+			}
+		})
+	}
+
+	matches, err := backend.Glob(ctx, offline, "vars/*.tfvars")
+	if err != nil {
+		t.Fatalf("Glob() offline error = %v", err)
+	}
+	if want := []string{"vars/a.tfvars"}; !reflect.DeepEqual(matches, want) {
+		t.Errorf("Glob() offline = %v, want %v", matches, want)
+	}
+}
+
+func TestWrapRevalidates(t *testing.T) {
+	c := New(t.TempDir())
+	inner := &revalidatingBackend{fakeBackend: fakeBackend{
+		files:    map[string]string{"a.tfvars": "a = 1\n"},
+		versions: map[string]string{"a.tfvars": `"v1"`},
+	}}
+	wrapped := c.Wrap(inner, "http", "id", "https://example.com/", false)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if content, err := wrapped.GetVarsFile(ctx, "a.tfvars"); err != nil || string(content) != "a = 1\n" {
+			t.Fatalf("GetVarsFile() = %q, %v, want the cached content", content, err)
+		}
+	}
+	if inner.downloads != 1 || inner.revalidations != 2 {
+		t.Errorf("downloaded %d times and revalidated %d times, want 1 and 2", inner.downloads, inner.revalidations)
+	}
+
+	inner.files["a.tfvars"] = "a = 2\n"
+	inner.versions["a.tfvars"] = `"v2"`
+	if content, err := wrapped.GetVarsFile(ctx, "a.tfvars"); err != nil || string(content) != "a = 2\n" {
+		t.Fatalf("GetVarsFile() after a change = %q, %v, want the new content", content, err)
+	}
+	if entry, _, err := c.Get("http", "id", "a.tfvars"); err != nil || entry.Version != `"v2"` {
+		t.Errorf("Get() = %+v, %v, want version \"v2\"", entry, err)
+	}
+}
+
+func TestWrapSecretStore(t *testing.T) {
+	c := New(t.TempDir())
+	secrets := &secretBackend{}
+
+	if got := c.Wrap(secrets, "vault", "id", "", false); got != backend.Backend(secrets) {
+		t.Errorf("Wrap() online returned %T, want the secret store itself", got)
+	}
+	if _, err := c.Wrap(secrets, "vault", "id", "", true).GetVarsFile(context.Background(), "app"); err == nil {
+		t.Error("GetVarsFile() offline from a secret store succeeded")
+	}
+}
@@ -0,0 +1,244 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/marcy326/tivor/internal/backend"
+)
+
+// GitBackend implements Backend interface for reading files from a git repository
+// at a fixed ref, without checking it out
+type GitBackend struct {
+	repository string
+	ref        string
+	basePath   string
+
+	mu     sync.Mutex
+	commit string
+	err    error
+}
+
+// New creates a new GitBackend instance.
+// The ref (branch, tag or commit SHA) is required; repository defaults to the
+// current directory and path is an optional directory inside the repository.
+func New(config backend.Config) (backend.Backend, error) {
+	ref, _ := config["ref"].(string)
+	if ref == "" {
+		return nil, fmt.Errorf("git backend requires a ref (branch, tag or commit)")
+	}
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid git ref %q: refs cannot start with '-'", ref)
+	}
+
+	repository := "."
+	if repo, ok := config["repository"].(string); ok && repo != "" {
+		repository = repo
+	}
+
+	absPath, err := filepath.Abs(repository)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve absolute path for %s: %w", repository, err)
+	}
+
+	basePath := ""
+	if base, ok := config["path"].(string); ok && base != "" {
+		basePath, err = cleanPath(base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &GitBackend{
+		repository: absPath,
+		ref:        ref,
+		basePath:   basePath,
+	}, nil
+}
+
+// GetVarsFile retrieves the content of a variable file at the configured ref
+func (g *GitBackend) GetVarsFile(ctx context.Context, path string) ([]byte, error) {
+	content, _, err := g.GetVersionedVarsFile(ctx, path)
+	return content, err
+}
+
+// GetVersionedVarsFile retrieves the content of a variable file at the configured ref
+// and its blob ID
+func (g *GitBackend) GetVersionedVarsFile(ctx context.Context, path string) ([]byte, string, error) {
+	commit, err := g.resolveCommit(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fullPath, err := g.fullPath(path)
+	if err != nil {
+		return nil, "", err
+	}
+	blob, _, err := g.lookup(ctx, commit, fullPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	content, err := g.run(ctx, "cat-file", "blob", blob)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read variable file %s at %s: %w", fullPath, g.ref, err)
+	}
+
+	return content, blob, nil
+}
+
+// List returns the files below the prefix directory at the configured ref in lexical order
+func (g *GitBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	commit, err := g.resolveCommit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fullPrefix, err := g.fullPath(prefix)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"ls-tree", "-r", "--name-only", "-z", commit}
+	if fullPrefix != "" {
+		args = append(args, "--", fullPrefix+"/")
+	}
+
+	output, err := g.run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files at %s: %w", g.ref, err)
+	}
+
+	var paths []string
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file == "" {
+			continue
+		}
+		// Return paths relative to the base path so they can be passed to GetVarsFile
+		if g.basePath != "" {
+			file = strings.TrimPrefix(file, g.basePath+"/")
+		}
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// Stat returns the size of a variable file at the configured ref.
+// Its version is the blob ID, which changes exactly when the content changes.
+func (g *GitBackend) Stat(ctx context.Context, path string) (*backend.FileInfo, error) {
+	commit, err := g.resolveCommit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fullPath, err := g.fullPath(path)
+	if err != nil {
+		return nil, err
+	}
+	blob, size, err := g.lookup(ctx, commit, fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &backend.FileInfo{
+		Path:    path,
+		Size:    size,
+		Version: blob,
+		ETag:    blob,
+	}, nil
+}
+
+// lookup returns the blob ID and size of a file in a commit.
+// Only a path missing from the commit is reported as backend.ErrNotFound.
+func (g *GitBackend) lookup(ctx context.Context, commit, fullPath string) (string, int64, error) {
+	// Output: <mode> SP <type> SP <object> SP+ <size> TAB <path>, or nothing if the path is missing
+	output, err := g.run(ctx, "ls-tree", "-l", "-z", commit, "--", fullPath)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to look up %s at %s: %w", fullPath, g.ref, err)
+	}
+	meta, _, ok := strings.Cut(string(output), "\t")
+	if !ok {
+		return "", 0, fmt.Errorf("%w: %s at %s", backend.ErrNotFound, fullPath, g.ref)
+	}
+	fields := strings.Fields(meta)
+	if len(fields) != 4 || fields[1] != "blob" {
+		return "", 0, fmt.Errorf("%s at %s is not a file", fullPath, g.ref)
+	}
+	size, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to look up %s at %s: invalid size %q", fullPath, g.ref, fields[3])
+	}
+	return fields[2], size, nil
+}
+
+// resolveCommit resolves the ref to a commit once, so every file is read from the same commit
+// even if a branch moves while tivor is running. Failures caused by ctx are not remembered.
+func (g *GitBackend) resolveCommit(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.commit != "" || g.err != nil {
+		return g.commit, g.err
+	}
+	output, err := g.run(ctx, "rev-parse", "--verify", "--quiet", g.ref+"^{commit}")
+	if err != nil {
+		err = fmt.Errorf("failed to resolve git ref %s in %s: %w", g.ref, g.repository, err)
+		if ctx.Err() == nil {
+			g.err = err
+		}
+		return "", err
+	}
+	g.commit = strings.TrimSpace(string(output))
+	return g.commit, nil
+}
+
+// fullPath resolves a path against the base path inside the repository
+func (g *GitBackend) fullPath(p string) (string, error) {
+	if filepath.IsAbs(p) {
+		return "", fmt.Errorf("git backend paths must be relative to the repository: %s", p)
+	}
+	return cleanPath(path.Join(g.basePath, filepath.ToSlash(p)))
+}
+
+// run executes a git command in the repository and returns its stdout
+func (g *GitBackend) run(ctx context.Context, args ...string) ([]byte, error) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return nil, fmt.Errorf("git binary not found in PATH: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, gitPath, append([]string{"-C", g.repository}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// cleanPath cleans a repository path and rejects paths leaving the repository
+func cleanPath(p string) (string, error) {
+	cleaned := path.Clean(filepath.ToSlash(p))
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.HasPrefix(cleaned, "/") {
+		return "", fmt.Errorf("path %s is outside the repository", p)
+	}
+	if cleaned == "." {
+		return "", nil
+	}
+	return cleaned, nil
+}
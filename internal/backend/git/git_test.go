@@ -0,0 +1,259 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/marcy326/tivor/internal/backend"
+)
+
+// newTestRepo creates a repository with a commit tagged v1 and a later commit on the branch
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not found in PATH")
+	}
+
+	dir := t.TempDir()
+	gitCmd(t, dir, "init", "--quiet")
+	writeFile(t, dir, "vars/common.tfvars", "region = \"eu-west-1\"\n")
+	writeFile(t, dir, "vars/prod/app.tfvars", "replicas = 3\n")
+	writeFile(t, dir, "vars/dev.tfvars", "replicas = 1\n")
+	gitCmd(t, dir, "add", ".")
+	gitCmd(t, dir, "commit", "--quiet", "-m", "v1")
+	gitCmd(t, dir, "tag", "v1")
+	writeFile(t, dir, "vars/common.tfvars", "region = \"us-east-1\"\n")
+	gitCmd(t, dir, "commit", "--quiet", "-am", "v2")
+	return dir
+}
+
+// gitCmd runs git in dir and returns its trimmed output
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// writeFile writes a file below dir, creating its directories
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  backend.Config
+		wantErr string
+	}{
+		{name: "ref only", config: backend.Config{"ref": "main"}},
+		{name: "missing ref", config: backend.Config{}, wantErr: "requires a ref"},
+		{name: "ref looking like an option", config: backend.Config{"ref": "--output=/tmp/x"}, wantErr: "cannot start with '-'"},
+		{name: "path outside the repository", config: backend.Config{"ref": "main", "path": "../other"}, wantErr: "outside the repository"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetVarsFile(t *testing.T) {
+	repo := newTestRepo(t)
+
+	tests := []struct {
+		name         string
+		config       backend.Config
+		path         string
+		want         string
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{name: "file at tag", config: backend.Config{"ref": "v1"}, path: "vars/common.tfvars", want: "region = \"eu-west-1\"\n"},
+		{name: "file at branch head", config: backend.Config{"ref": "HEAD"}, path: "vars/common.tfvars", want: "region = \"us-east-1\"\n"},
+		{name: "base path", config: backend.Config{"ref": "v1", "path": "vars"}, path: "prod/app.tfvars", want: "replicas = 3\n"},
+		{name: "missing file", config: backend.Config{"ref": "v1"}, path: "vars/missing.tfvars", wantNotFound: true},
+		{name: "directory", config: backend.Config{"ref": "v1"}, path: "vars/prod", wantErr: true},
+		{name: "unknown ref", config: backend.Config{"ref": "v9"}, path: "vars/common.tfvars", wantErr: true},
+		{name: "absolute path", config: backend.Config{"ref": "v1"}, path: "/etc/passwd", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := backend.Config{"repository": repo}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			b, err := New(config)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			content, err := b.GetVarsFile(context.Background(), tt.path)
+			switch {
+			case tt.wantNotFound:
+				if !errors.Is(err, backend.ErrNotFound) {
+					t.Fatalf("GetVarsFile() error = %v, want ErrNotFound", err)
+				}
+			case tt.wantErr:
+				if err == nil || errors.Is(err, backend.ErrNotFound) {
+					t.Fatalf("GetVarsFile() error = %v, want an error other than ErrNotFound", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("GetVarsFile() error = %v", err)
+				}
+				if string(content) != tt.want {
+					t.Errorf("GetVarsFile() = %q, want %q", content, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetVarsFileNotARepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not found in PATH")
+	}
+	b, err := New(backend.Config{"repository": t.TempDir(), "ref": "main"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := b.GetVarsFile(context.Background(), "common.tfvars"); err == nil || errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("GetVarsFile() error = %v, want an error other than ErrNotFound", err)
+	}
+}
+
+func TestResolveCommitPinned(t *testing.T) {
+	repo := newTestRepo(t)
+	b, err := New(backend.Config{"repository": repo, "ref": "HEAD"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// A canceled context must not be remembered
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.GetVarsFile(canceled, "vars/common.tfvars"); err == nil || errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("GetVarsFile() with a canceled context error = %v, want a context error", err)
+	}
+
+	ctx := context.Background()
+	first, err := b.GetVarsFile(ctx, "vars/common.tfvars")
+	if err != nil {
+		t.Fatalf("GetVarsFile() error = %v", err)
+	}
+
+	// Files are read from the commit resolved first, even if the branch moves
+	writeFile(t, repo, "vars/common.tfvars", "region = \"ap-south-1\"\n")
+	gitCmd(t, repo, "commit", "--quiet", "-am", "v3")
+	second, err := b.GetVarsFile(ctx, "vars/common.tfvars")
+	if err != nil {
+		t.Fatalf("GetVarsFile() error = %v", err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("content changed from %q to %q after the branch moved", first, second)
+	}
+}
+
+func TestList(t *testing.T) {
+	repo := newTestRepo(t)
+
+	tests := []struct {
+		name   string
+		config backend.Config
+		prefix string
+		want   []string
+	}{
+		{name: "whole tree", config: backend.Config{"ref": "v1"}, want: []string{"vars/common.tfvars", "vars/dev.tfvars", "vars/prod/app.tfvars"}},
+		{name: "prefix", config: backend.Config{"ref": "v1"}, prefix: "vars/prod", want: []string{"vars/prod/app.tfvars"}},
+		{name: "base path", config: backend.Config{"ref": "v1", "path": "vars"}, prefix: "prod", want: []string{"prod/app.tfvars"}},
+		{name: "missing prefix", config: backend.Config{"ref": "v1"}, prefix: "other", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := backend.Config{"repository": repo}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			b, err := New(config)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			got, err := b.(backend.Lister).List(context.Background(), tt.prefix)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlob(t *testing.T) {
+	repo := newTestRepo(t)
+	b, err := New(backend.Config{"repository": repo, "ref": "v1"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	got, err := backend.Glob(context.Background(), b, "vars/*.tfvars")
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	if want := []string{"vars/common.tfvars", "vars/dev.tfvars"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Glob() = %v, want %v", got, want)
+	}
+}
+
+func TestStat(t *testing.T) {
+	repo := newTestRepo(t)
+	b, err := New(backend.Config{"repository": repo, "ref": "v1"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	stater := b.(backend.Stater)
+
+	info, err := stater.Stat(context.Background(), "vars/common.tfvars")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if want := gitCmd(t, repo, "rev-parse", "v1:vars/common.tfvars"); info.Version != want {
+		t.Errorf("Version = %q, want blob %q", info.Version, want)
+	}
+	if want := int64(len("region = \"eu-west-1\"\n")); info.Size != want {
+		t.Errorf("Size = %d, want %d", info.Size, want)
+	}
+
+	if _, err := stater.Stat(context.Background(), "vars/missing.tfvars"); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("Stat() of a missing file error = %v, want ErrNotFound", err)
+	}
+}
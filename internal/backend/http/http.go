@@ -0,0 +1,352 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	nethttp "net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marcy326/tivor/internal/backend"
+)
+
+const (
+	// DefaultTimeout is the timeout of each request
+	DefaultTimeout = 30 * time.Second
+	// DefaultRetries is how many times a failed request is retried
+	DefaultRetries = 2
+)
+
+// initialBackoff is the delay before the first retry; it doubles on each retry
+var initialBackoff = 500 * time.Millisecond
+
+// errNotModified is returned by fetch when the server answers a conditional request with 304
+var errNotModified = errors.New("not modified")
+
+// HTTPBackend implements Backend interface for variable files served over HTTP(S)
+type HTTPBackend struct {
+	baseURL *url.URL
+	client  *nethttp.Client
+	auth    func(req *nethttp.Request)
+	retries int
+}
+
+// New creates a new HTTPBackend instance.
+//
+// Configuration keys:
+//
+//	base_url   URL that vars file paths are resolved against (required)
+//	auth       {type: bearer, token_env: NAME} or {type: basic, username_env: NAME, password_env: NAME}
+//	timeout    timeout of each request (default 30s)
+//	retries    retries of failed requests (default 2)
+//
+// Downloaded files are cached by the tivor cache, which revalidates them with their ETag.
+func New(config backend.Config) (backend.Backend, error) {
+	rawURL, _ := config["base_url"].(string)
+	if rawURL == "" {
+		return nil, fmt.Errorf("http backend requires a base_url")
+	}
+	// Resolve paths below the base URL rather than replacing its last segment
+	if !strings.HasSuffix(rawURL, "/") {
+		rawURL += "/"
+	}
+	baseURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base_url %s: %w", rawURL, err)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base_url %s: scheme must be http or https", rawURL)
+	}
+
+	timeout := DefaultTimeout
+	if value, ok := config["timeout"]; ok {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid timeout %v: must be a duration such as 30s", value)
+		}
+		if s != "" {
+			if timeout, err = time.ParseDuration(s); err != nil {
+				return nil, fmt.Errorf("invalid timeout %q: %w", s, err)
+			}
+		}
+	}
+
+	retries := DefaultRetries
+	if value, ok := config["retries"]; ok {
+		n, ok := value.(int)
+		if !ok || n < 0 {
+			return nil, fmt.Errorf("invalid retries %v: must be a non-negative integer", value)
+		}
+		retries = n
+	}
+
+	auth, err := newAuth(config["auth"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPBackend{
+		baseURL: baseURL,
+		client:  &nethttp.Client{Timeout: timeout},
+		auth:    auth,
+		retries: retries,
+	}, nil
+}
+
+// GetVarsFile downloads a variable file
+func (h *HTTPBackend) GetVarsFile(ctx context.Context, path string) ([]byte, error) {
+	content, _, err := h.GetVersionedVarsFile(ctx, path)
+	return content, err
+}
+
+// GetVersionedVarsFile downloads a variable file like GetVarsFile and returns the ETag
+// of the response, which is empty if the server sends none
+func (h *HTTPBackend) GetVersionedVarsFile(ctx context.Context, path string) ([]byte, string, error) {
+	return h.get(ctx, path, "")
+}
+
+// GetVarsFileIfChanged downloads a variable file like GetVersionedVarsFile, unless the
+// server reports with 304 Not Modified that its ETag is still etag
+func (h *HTTPBackend) GetVarsFileIfChanged(ctx context.Context, path, etag string) ([]byte, string, bool, error) {
+	content, newETag, err := h.get(ctx, path, etag)
+	if errors.Is(err, errNotModified) {
+		return nil, etag, true, nil
+	}
+	if err != nil {
+		return nil, "", false, err
+	}
+	return content, newETag, false, nil
+}
+
+// get downloads a variable file with retries, sending If-None-Match if etag is set
+func (h *HTTPBackend) get(ctx context.Context, path, etag string) ([]byte, string, error) {
+	fileURL, err := h.resolve(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var content []byte
+	var newETag string
+	err = h.retry(ctx, fileURL, func() (bool, error) {
+		var retryable bool
+		content, newETag, retryable, err = h.fetch(ctx, fileURL, etag)
+		return retryable, err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return content, newETag, nil
+}
+
+// Stat describes a variable file with a HEAD request.
+// Its version is the ETag, so it is empty if the server sends none.
+func (h *HTTPBackend) Stat(ctx context.Context, path string) (*backend.FileInfo, error) {
+	fileURL, err := h.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var info *backend.FileInfo
+	err = h.retry(ctx, fileURL, func() (bool, error) {
+		var retryable bool
+		info, retryable, err = h.head(ctx, fileURL)
+		return retryable, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	info.Path = path
+	return info, nil
+}
+
+// retry runs a request until it succeeds, fails permanently or runs out of retries
+func (h *HTTPBackend) retry(ctx context.Context, fileURL string, request func() (retryable bool, err error)) error {
+	for attempt := 0; ; attempt++ {
+		retryable, err := request()
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= h.retries || ctx.Err() != nil {
+			return err
+		}
+
+		backoff := initialBackoff << attempt
+		slog.Warn("Retrying vars file request", "url", displayURL(fileURL), "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("request for %s interrupted: %w", displayURL(fileURL), ctx.Err())
+		}
+	}
+}
+
+// head performs a single HEAD request. It reports whether a failure may succeed on retry.
+func (h *HTTPBackend) head(ctx context.Context, fileURL string) (*backend.FileInfo, bool, error) {
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodHead, fileURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request for %s: %w", displayURL(fileURL), err)
+	}
+	if h.auth != nil {
+		h.auth(req)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to stat %s: %w", displayURL(fileURL), err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == nethttp.StatusOK:
+		info := &backend.FileInfo{
+			Size:    resp.ContentLength,
+			ETag:    resp.Header.Get("ETag"),
+			Version: resp.Header.Get("ETag"),
+		}
+		if modTime, err := nethttp.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+			info.ModTime = modTime
+		}
+		return info, false, nil
+
+	case resp.StatusCode == nethttp.StatusNotFound:
+		return nil, false, fmt.Errorf("%w: %s", backend.ErrNotFound, displayURL(fileURL))
+
+	case resp.StatusCode == nethttp.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, true, fmt.Errorf("failed to stat %s: %s", displayURL(fileURL), resp.Status)
+
+	default:
+		return nil, false, fmt.Errorf("failed to stat %s: %s", displayURL(fileURL), resp.Status)
+	}
+}
+
+// fetch performs a single request and returns the content and its ETag, or errNotModified
+// if etag is set and still current. It reports whether a failure may succeed on retry.
+func (h *HTTPBackend) fetch(ctx context.Context, fileURL, etag string) ([]byte, string, bool, error) {
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to create request for %s: %w", displayURL(fileURL), err)
+	}
+	if h.auth != nil {
+		h.auth(req)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, "", true, fmt.Errorf("failed to download %s: %w", displayURL(fileURL), err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == nethttp.StatusNotModified && etag != "":
+		return nil, "", false, errNotModified
+
+	case resp.StatusCode == nethttp.StatusOK:
+		content, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, "", true, fmt.Errorf("failed to download %s: %w", displayURL(fileURL), err)
+		}
+		return content, resp.Header.Get("ETag"), false, nil
+
+	case resp.StatusCode == nethttp.StatusNotFound:
+		return nil, "", false, fmt.Errorf("%w: %s", backend.ErrNotFound, displayURL(fileURL))
+
+	case resp.StatusCode == nethttp.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, "", true, fmt.Errorf("failed to download %s: %s", displayURL(fileURL), resp.Status)
+
+	default:
+		return nil, "", false, fmt.Errorf("failed to download %s: %s", displayURL(fileURL), resp.Status)
+	}
+}
+
+// resolve returns the URL of a vars file path below the base URL
+func (h *HTTPBackend) resolve(p string) (string, error) {
+	ref, err := url.Parse(filepath.ToSlash(p))
+	if err != nil {
+		return "", fmt.Errorf("invalid vars file path %s: %w", p, err)
+	}
+	// Credentials must never be sent to another host or path
+	if ref.IsAbs() || ref.Host != "" {
+		return "", fmt.Errorf("vars file path %s must be relative to the base_url", p)
+	}
+	// Leading slashes are relative to the base URL too
+	clean := path.Clean(strings.TrimLeft(ref.Path, "/"))
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("vars file path %s must be a file below the base_url", p)
+	}
+	ref.Path, ref.RawPath = clean, ""
+
+	resolved := h.baseURL.ResolveReference(ref)
+	if resolved.Host != h.baseURL.Host || !strings.HasPrefix(resolved.Path, h.baseURL.Path) {
+		return "", fmt.Errorf("vars file path %s must be a file below the base_url", p)
+	}
+	return resolved.String(), nil
+}
+
+// newAuth returns a function adding credentials from environment variables to requests
+func newAuth(value interface{}) (func(req *nethttp.Request), error) {
+	if value == nil {
+		return nil, nil
+	}
+	config, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("auth must be a mapping")
+	}
+
+	lookup := func(key string) (string, error) {
+		name, _ := config[key].(string)
+		if name == "" {
+			return "", fmt.Errorf("%s auth requires %s", config["type"], key)
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			return "", fmt.Errorf("environment variable %s for http backend auth is not set", name)
+		}
+		return value, nil
+	}
+
+	switch config["type"] {
+	case "bearer":
+		token, err := lookup("token_env")
+		if err != nil {
+			return nil, err
+		}
+		return func(req *nethttp.Request) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}, nil
+
+	case "basic":
+		username, err := lookup("username_env")
+		if err != nil {
+			return nil, err
+		}
+		password, err := lookup("password_env")
+		if err != nil {
+			return nil, err
+		}
+		return func(req *nethttp.Request) {
+			req.SetBasicAuth(username, password)
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown auth type %v (expected bearer or basic)", config["type"])
+	}
+}
+
+// displayURL returns a URL with its password masked, for logs and errors
+func displayURL(fileURL string) string {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return fileURL
+	}
+	return u.Redacted()
+}
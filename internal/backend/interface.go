@@ -1,6 +1,19 @@
 package backend
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+// ErrNotFound is returned (wrapped) when a requested variable file does not exist.
+var ErrNotFound = errors.New("variable file not found")
 
 // Config is generic configuration passed to each backend implementation.
 type Config map[string]interface{}
@@ -13,3 +26,116 @@ type Backend interface {
 	// For local backends (like git), this returns the local path.
 	GetVarsFile(ctx context.Context, path string) (content []byte, err error)
 }
+
+// Globber is implemented by backends that can expand glob patterns.
+type Globber interface {
+	// Glob returns the paths matching pattern in lexical order.
+	// The returned paths can be passed to GetVarsFile.
+	Glob(ctx context.Context, pattern string) ([]string, error)
+}
+
+// VariablesGetter is implemented by backends that produce variables directly
+// rather than tfvars files, such as secret stores. Their variables may be sensitive.
+type VariablesGetter interface {
+	// GetVariables retrieves the variables stored at the specified path.
+	GetVariables(ctx context.Context, path string) ([]tfvars.Variable, error)
+}
+
+// VersionedGetter is implemented by backends that can report the version of the content
+// they return, so the version always describes exactly that content.
+type VersionedGetter interface {
+	// GetVersionedVarsFile retrieves a variable file like GetVarsFile, along with the
+	// version of the content, or its ETag if it has no version. Both may be empty.
+	GetVersionedVarsFile(ctx context.Context, path string) (content []byte, version string, err error)
+}
+
+// ConditionalGetter is implemented by backends that can revalidate a copy the caller
+// already has, such as HTTP servers answering If-None-Match.
+type ConditionalGetter interface {
+	// GetVarsFileIfChanged retrieves a variable file like GetVersionedVarsFile, unless
+	// its version is still version: then it reports unchanged and returns no content.
+	GetVarsFileIfChanged(ctx context.Context, path, version string) (content []byte, newVersion string, unchanged bool, err error)
+}
+
+// GetVersioned retrieves a variable file and, if the backend is a VersionedGetter, its version.
+func GetVersioned(ctx context.Context, b Backend, path string) ([]byte, string, error) {
+	if getter, ok := b.(VersionedGetter); ok {
+		return getter.GetVersionedVarsFile(ctx, path)
+	}
+	content, err := b.GetVarsFile(ctx, path)
+	return content, "", err
+}
+
+// FileInfo describes a file stored in a backend.
+type FileInfo struct {
+	Path string
+	Size int64
+	// Version identifies the content, such as a git blob ID; empty if the backend has none
+	Version string
+	ModTime time.Time
+	// ETag changes whenever the content changes
+	ETag string
+}
+
+// Lister is implemented by backends that can list their files.
+type Lister interface {
+	// List returns the paths of the files below the directory prefix in lexical order.
+	// An empty prefix lists every file. The returned paths can be passed to GetVarsFile.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Stater is implemented by backends that can describe a file without downloading it.
+type Stater interface {
+	// Stat returns the metadata of the file at the specified path.
+	Stat(ctx context.Context, path string) (*FileInfo, error)
+}
+
+// Putter is implemented by backends that can store files.
+type Putter interface {
+	// Put stores content at the specified path, replacing any existing file.
+	Put(ctx context.Context, path string, content []byte) error
+}
+
+// Glob expands a glob pattern with the backend's Globber, or by matching the
+// files listed by its Lister. Patterns match path segments as in path.Match.
+func Glob(ctx context.Context, b Backend, pattern string) ([]string, error) {
+	if globber, ok := b.(Globber); ok {
+		return globber.Glob(ctx, pattern)
+	}
+	lister, ok := b.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("backend does not support glob patterns")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %s: %w", pattern, err)
+	}
+
+	// Only list the directory before the first segment with a metacharacter
+	var prefix []string
+	for _, segment := range strings.Split(pattern, "/") {
+		if strings.ContainsAny(segment, "*?[\\") {
+			break
+		}
+		prefix = append(prefix, segment)
+	}
+	if len(prefix) == strings.Count(pattern, "/")+1 {
+		prefix = prefix[:len(prefix)-1]
+	}
+	dir := strings.Join(prefix, "/")
+	if dir == "" && strings.HasPrefix(pattern, "/") {
+		dir = "/"
+	}
+
+	files, err := lister.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, file := range files {
+		if matched, _ := path.Match(pattern, file); matched {
+			matches = append(matches, file)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
@@ -2,9 +2,13 @@ package local
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/marcy326/tivor/internal/backend"
 )
@@ -34,17 +38,11 @@ func New(config backend.Config) (backend.Backend, error) {
 
 // GetVarsFile retrieves the content of a variable file from local filesystem
 func (l *LocalBackend) GetVarsFile(ctx context.Context, path string) ([]byte, error) {
-	// Handle both absolute and relative paths
-	var fullPath string
-	if filepath.IsAbs(path) {
-		fullPath = path
-	} else {
-		fullPath = filepath.Join(l.basePath, path)
-	}
+	fullPath := l.fullPath(path)
 
 	// Check if file exists
 	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("variable file not found: %s", fullPath)
+		return nil, fmt.Errorf("%w: %s", backend.ErrNotFound, fullPath)
 	}
 
 	// Read file content
@@ -55,3 +53,104 @@ func (l *LocalBackend) GetVarsFile(ctx context.Context, path string) ([]byte, er
 
 	return content, nil
 }
+
+// List returns the files below the prefix directory in lexical order.
+// Relative prefixes yield paths relative to the base path. Hidden directories
+// below the prefix, such as .git and .terraform, are skipped.
+func (l *LocalBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	root := l.fullPath(prefix)
+
+	var paths []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() {
+			if p != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !filepath.IsAbs(prefix) {
+			if rel, err := filepath.Rel(l.basePath, p); err == nil {
+				p = filepath.ToSlash(rel)
+			}
+		}
+		paths = append(paths, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", root, err)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// Stat returns the size and modification time of a variable file.
+// The ETag is derived from both, like the ETags of static file servers.
+func (l *LocalBackend) Stat(ctx context.Context, path string) (*backend.FileInfo, error) {
+	fullPath := l.fullPath(path)
+
+	info, err := os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", backend.ErrNotFound, fullPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat variable file %s: %w", fullPath, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", fullPath)
+	}
+
+	return &backend.FileInfo{
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		ETag:    fmt.Sprintf("\"%x-%x\"", info.ModTime().UnixNano(), info.Size()),
+	}, nil
+}
+
+// Put writes a file, creating its parent directories.
+// The content is written to a temporary file first, so readers never see a partial file.
+func (l *LocalBackend) Put(ctx context.Context, path string, content []byte) error {
+	fullPath := l.fullPath(path)
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", fullPath, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".tivor-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", fullPath, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", fullPath, err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", fullPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fullPath, err)
+	}
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fullPath, err)
+	}
+
+	return nil
+}
+
+// fullPath resolves a path against the base path unless it is absolute
+func (l *LocalBackend) fullPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(l.basePath, path)
+}
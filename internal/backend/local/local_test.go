@@ -0,0 +1,113 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/marcy326/tivor/internal/backend"
+)
+
+// newTestDir creates a directory of vars files, including some in hidden directories
+func newTestDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"common.tfvars":           "a = 1\n",
+		"env/dev.tfvars":          "b = 2\n",
+		"env/prod.tfvars":         "b = 3\n",
+		"env/nested/extra.tfvars": "c = 4\n",
+		".terraform/cache.tfvars": "ignored = true\n",
+		"env/.git/objects.tfvars": "ignored = true\n",
+		"env/readme.md":           "not vars\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGlob(t *testing.T) {
+	dir := newTestDir(t)
+	b, err := New(backend.Config{"path": dir})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{name: "root", pattern: "*.tfvars", want: []string{"common.tfvars"}},
+		{name: "directory", pattern: "env/*.tfvars", want: []string{"env/dev.tfvars", "env/prod.tfvars"}},
+		{name: "wildcard directory", pattern: "*/nested/*.tfvars", want: []string{"env/nested/extra.tfvars"}},
+		{name: "hidden directory", pattern: "*/*.tfvars", want: []string{"env/dev.tfvars", "env/prod.tfvars"}},
+		{name: "explicit hidden directory", pattern: ".terraform/*.tfvars", want: []string{".terraform/cache.tfvars"}},
+		{name: "absolute", pattern: filepath.ToSlash(dir) + "/env/d*.tfvars", want: []string{filepath.ToSlash(dir) + "/env/dev.tfvars"}},
+		{name: "missing directory", pattern: "missing/*.tfvars", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := backend.Glob(context.Background(), b, tt.pattern)
+			if err != nil {
+				t.Fatalf("Glob() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Glob(%s) = %v, want %v", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetVarsFile(t *testing.T) {
+	dir := newTestDir(t)
+	b, err := New(backend.Config{"path": dir})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	content, err := b.GetVarsFile(context.Background(), "env/dev.tfvars")
+	if err != nil || string(content) != "b = 2\n" {
+		t.Errorf("GetVarsFile() = %q, %v", content, err)
+	}
+	if _, err := b.GetVarsFile(context.Background(), "missing.tfvars"); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("GetVarsFile() of a missing file error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStatAndPut(t *testing.T) {
+	dir := t.TempDir()
+	b, err := New(backend.Config{"path": dir})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := b.(backend.Stater).Stat(ctx, "published/dev.tfvars"); !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("Stat() before Put error = %v, want ErrNotFound", err)
+	}
+	if err := b.(backend.Putter).Put(ctx, "published/dev.tfvars", []byte("a = 1\n")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	info, err := b.(backend.Stater).Stat(ctx, "published/dev.tfvars")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size != 6 || info.ETag == "" {
+		t.Errorf("Stat() = %+v, want size 6 and an ETag", info)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "published", "dev.tfvars"))
+	if err != nil || string(content) != "a = 1\n" {
+		t.Errorf("published file = %q, %v", content, err)
+	}
+}
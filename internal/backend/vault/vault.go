@@ -0,0 +1,449 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcy326/tivor/internal/backend"
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+// DefaultTimeout is the timeout of each request to Vault
+const DefaultTimeout = 30 * time.Second
+
+// VaultBackend implements Backend interface for secrets stored in a HashiCorp Vault KV v2 engine.
+// Each path is a secret whose keys become sensitive variables.
+type VaultBackend struct {
+	address   string
+	mount     string
+	namespace string
+	client    *http.Client
+	login     func(ctx context.Context) (string, error)
+	keys      map[string]keyMapping
+
+	mu    sync.Mutex
+	token string
+	err   error
+}
+
+// keyMapping renames and converts a single key of a secret
+type keyMapping struct {
+	Name string
+	Type string
+}
+
+// New creates a new VaultBackend instance.
+//
+// Configuration keys:
+//
+//	address    Vault address (default: VAULT_ADDR)
+//	namespace  Vault Enterprise namespace (default: VAULT_NAMESPACE)
+//	mount      KV v2 mount path (default: secret)
+//	auth       {method: token, token_env: NAME} (default, token from VAULT_TOKEN) or
+//	           {method: approle, mount: approle, role_id_env: NAME, secret_id_env: NAME}
+//	keys       per-key mapping: {key: {name: variable_name, type: string|number|bool|json}};
+//	           a string value only renames the key
+//	timeout    timeout of each request (default 30s)
+func New(config backend.Config) (backend.Backend, error) {
+	address, _ := config["address"].(string)
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("vault backend requires an address (or VAULT_ADDR)")
+	}
+	if _, err := url.Parse(address); err != nil {
+		return nil, fmt.Errorf("invalid vault address %s: %w", address, err)
+	}
+
+	mount := "secret"
+	if value, ok := config["mount"].(string); ok && value != "" {
+		mount = strings.Trim(value, "/")
+	}
+
+	namespace, _ := config["namespace"].(string)
+	if namespace == "" {
+		namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+
+	timeout := DefaultTimeout
+	if value, ok := config["timeout"].(string); ok && value != "" {
+		var err error
+		if timeout, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %w", value, err)
+		}
+	}
+
+	keys, err := parseKeys(config["keys"])
+	if err != nil {
+		return nil, err
+	}
+
+	v := &VaultBackend{
+		address:   strings.TrimSuffix(address, "/"),
+		mount:     mount,
+		namespace: namespace,
+		client:    &http.Client{Timeout: timeout},
+		keys:      keys,
+	}
+
+	if v.login, err = v.newLogin(config["auth"]); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// GetVariables reads a secret and converts its keys into sensitive variables sorted by name
+func (v *VaultBackend) GetVariables(ctx context.Context, path string) ([]tfvars.Variable, error) {
+	data, err := v.readSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	source := fmt.Sprintf("vault:%s/%s", v.mount, path)
+	variables := make([]tfvars.Variable, 0, len(keys))
+	for _, key := range keys {
+		mapping := v.keys[key]
+		name := key
+		if mapping.Name != "" {
+			name = mapping.Name
+		}
+		// Names are written verbatim into tfvars lines and TF_VAR_ variables
+		if !tfvars.IsVariableName(name) {
+			return nil, fmt.Errorf("secret %s key %q: invalid variable name %q", path, key, name)
+		}
+
+		value, err := convert(data[key], mapping.Type)
+		if err != nil {
+			// Never include the value in the error
+			return nil, fmt.Errorf("secret %s key %s: %w", path, key, err)
+		}
+
+		variable, err := tfvars.FromValue(name, value)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s key %s: unsupported value", path, key)
+		}
+		variable.Source = source
+		variable.Sensitive = true
+		variables = append(variables, variable)
+	}
+
+	slog.Debug("Read secret from Vault", "path", source, "keys", len(variables))
+	return variables, nil
+}
+
+// GetVarsFile renders a secret as tfvars content
+func (v *VaultBackend) GetVarsFile(ctx context.Context, path string) ([]byte, error) {
+	variables, err := v.GetVariables(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var builder strings.Builder
+	for _, variable := range variables {
+		builder.WriteString(fmt.Sprintf("%s = %s\n", variable.Name, variable.Value))
+	}
+	return []byte(builder.String()), nil
+}
+
+// readSecret reads the latest version of a KV v2 secret
+func (v *VaultBackend) readSecret(ctx context.Context, path string) (map[string]interface{}, error) {
+	token, err := v.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path = strings.Trim(path, "/")
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s", v.address, v.mount, path)
+
+	var response struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	status, err := v.request(ctx, http.MethodGet, endpoint, token, nil, &response)
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: vault secret %s/%s", backend.ErrNotFound, v.mount, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s/%s: %w", v.mount, path, err)
+	}
+	// Deleted and destroyed versions have no data
+	if response.Data.Data == nil {
+		return nil, fmt.Errorf("%w: vault secret %s/%s has no data", backend.ErrNotFound, v.mount, path)
+	}
+
+	return response.Data.Data, nil
+}
+
+// authenticate returns the Vault token, logging in once. Failures caused by ctx are not remembered.
+func (v *VaultBackend) authenticate(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.token != "" || v.err != nil {
+		return v.token, v.err
+	}
+	token, err := v.login(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			v.err = err
+		}
+		return "", err
+	}
+	v.token = token
+	return token, nil
+}
+
+// newLogin returns the function obtaining a Vault token for the configured auth method
+func (v *VaultBackend) newLogin(value interface{}) (func(ctx context.Context) (string, error), error) {
+	config, _ := value.(map[string]interface{})
+	if value != nil && config == nil {
+		return nil, fmt.Errorf("auth must be a mapping")
+	}
+
+	setting := func(key, fallback string) string {
+		if s, ok := config[key].(string); ok && s != "" {
+			return s
+		}
+		return fallback
+	}
+	lookup := func(name string) (string, error) {
+		value := os.Getenv(name)
+		if value == "" {
+			return "", fmt.Errorf("environment variable %s for vault auth is not set", name)
+		}
+		return value, nil
+	}
+
+	switch method := setting("method", "token"); method {
+	case "token":
+		tokenEnv := setting("token_env", "VAULT_TOKEN")
+		return func(ctx context.Context) (string, error) {
+			return lookup(tokenEnv)
+		}, nil
+
+	case "approle":
+		mount := strings.Trim(setting("mount", "approle"), "/")
+		roleIDEnv := setting("role_id_env", "VAULT_ROLE_ID")
+		secretIDEnv := setting("secret_id_env", "VAULT_SECRET_ID")
+		return func(ctx context.Context) (string, error) {
+			roleID, err := lookup(roleIDEnv)
+			if err != nil {
+				return "", err
+			}
+			secretID, err := lookup(secretIDEnv)
+			if err != nil {
+				return "", err
+			}
+
+			var response struct {
+				Auth struct {
+					ClientToken string `json:"client_token"`
+				} `json:"auth"`
+			}
+			body := map[string]string{"role_id": roleID, "secret_id": secretID}
+			endpoint := fmt.Sprintf("%s/v1/auth/%s/login", v.address, mount)
+			if _, err := v.request(ctx, http.MethodPost, endpoint, "", body, &response); err != nil {
+				return "", fmt.Errorf("vault approle login failed: %w", err)
+			}
+			if response.Auth.ClientToken == "" {
+				return "", fmt.Errorf("vault approle login returned no token")
+			}
+			slog.Debug("Logged in to Vault", "method", "approle", "mount", mount)
+			return response.Auth.ClientToken, nil
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown vault auth method %q (expected token or approle)", method)
+	}
+}
+
+// request sends a request to Vault and decodes the JSON response into out.
+// It returns the status code, which is 0 if no response was received.
+func (v *VaultBackend) request(ctx context.Context, method, endpoint, token string, body, out interface{}) (int, error) {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return 0, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		if decoder.Decode(&failure) == nil && len(failure.Errors) > 0 {
+			return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, strings.Join(failure.Errors, "; "))
+		}
+		return resp.StatusCode, fmt.Errorf("%s", resp.Status)
+	}
+
+	if err := decoder.Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// parseKeys parses the per-key mapping of the keys setting
+func parseKeys(value interface{}) (map[string]keyMapping, error) {
+	keys := make(map[string]keyMapping)
+	if value == nil {
+		return keys, nil
+	}
+
+	config, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("keys must be a mapping")
+	}
+
+	for key, raw := range config {
+		var mapping keyMapping
+		switch m := raw.(type) {
+		case string:
+			mapping.Name = m
+		case map[string]interface{}:
+			mapping.Name, _ = m["name"].(string)
+			mapping.Type, _ = m["type"].(string)
+		default:
+			return nil, fmt.Errorf("keys.%s must be a variable name or a mapping", key)
+		}
+
+		switch mapping.Type {
+		case "", "string", "number", "bool", "json":
+		default:
+			return nil, fmt.Errorf("keys.%s has unknown type %q (expected string, number, bool or json)", key, mapping.Type)
+		}
+		keys[key] = mapping
+	}
+
+	return keys, nil
+}
+
+// convert converts a secret value to the requested type. Without a type,
+// the JSON type stored in Vault is kept.
+func convert(value interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case "":
+		return normalize(value), nil
+
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case json.Number:
+			return v.String(), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		default:
+			return nil, fmt.Errorf("cannot convert %T to string", value)
+		}
+
+	case "number":
+		s := fmt.Sprint(value)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value is not a number")
+		}
+		return f, nil
+
+	case "bool":
+		b, err := strconv.ParseBool(fmt.Sprint(value))
+		if err != nil {
+			return nil, fmt.Errorf("value is not a bool")
+		}
+		return b, nil
+
+	case "json":
+		s, ok := value.(string)
+		if !ok {
+			return normalize(value), nil
+		}
+		decoder := json.NewDecoder(strings.NewReader(s))
+		decoder.UseNumber()
+		var decoded interface{}
+		if err := decoder.Decode(&decoded); err != nil {
+			return nil, fmt.Errorf("value is not valid JSON")
+		}
+		return normalize(decoded), nil
+
+	default:
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+}
+
+// normalize converts json.Number values into int64 or float64
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalize(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = normalize(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
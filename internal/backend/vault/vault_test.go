@@ -0,0 +1,239 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/marcy326/tivor/internal/backend"
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+// kvServer is a minimal stand-in for a Vault server with a KV v2 engine mounted at secret
+// and AppRole auth mounted at approle
+type kvServer struct {
+	*httptest.Server
+	secrets map[string]map[string]interface{}
+	logins  atomic.Int32
+}
+
+func newKVServer(t *testing.T, secrets map[string]map[string]interface{}) *kvServer {
+	t.Helper()
+	s := &kvServer{secrets: secrets}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *kvServer) handle(w http.ResponseWriter, r *http.Request) {
+	reply := func(status int, body interface{}) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+
+	if r.Method == http.MethodPost && r.URL.Path == "/v1/auth/approle/login" {
+		s.logins.Add(1)
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["role_id"] != "role" || body["secret_id"] != "secret" {
+			reply(http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid role or secret ID"}})
+			return
+		}
+		reply(http.StatusOK, map[string]interface{}{"auth": map[string]string{"client_token": "approle-token"}})
+		return
+	}
+
+	token := r.Header.Get("X-Vault-Token")
+	if token != "root" && token != "approle-token" {
+		reply(http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/v1/secret/data/")
+	if !ok || r.Method != http.MethodGet {
+		reply(http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+	data, ok := s.secrets[path]
+	if !ok {
+		reply(http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+	// A deleted latest version is returned with null data
+	reply(http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"data": data}})
+}
+
+func TestGetVariables(t *testing.T) {
+	server := newKVServer(t, map[string]map[string]interface{}{
+		"app/prod": {
+			"db_password": "hunter22",
+			"replicas":    "3",
+			"enabled":     true,
+			"limits":      `{"cpu": 2}`,
+			"region":      "eu-west-1",
+		},
+		"app/deleted": nil,
+		"app/bad":     {"replicas": "three-hunter22"},
+		"app/inject":  {"a = 1\nb": "hunter22"},
+		"app/space":   {"x y": "hunter22"},
+		"app/mapped":  {"renamed": "hunter22"},
+	})
+	t.Setenv("VAULT_TOKEN", "root")
+
+	keys := map[string]interface{}{
+		"replicas": map[string]interface{}{"type": "number"},
+		"limits":   map[string]interface{}{"type": "json"},
+		"region":   "aws_region",
+		"renamed":  "not a name",
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		want         []tfvars.Variable
+		wantNotFound bool
+		wantErr      string
+	}{
+		{
+			name: "secret",
+			path: "app/prod",
+			want: []tfvars.Variable{
+				{Name: "db_password", Value: `"hunter22"`, Type: tfvars.StringType},
+				{Name: "enabled", Value: "true", Type: tfvars.BoolType},
+				{Name: "limits", Value: "{\n  cpu = 2\n}", Type: tfvars.ObjectType},
+				{Name: "aws_region", Value: `"eu-west-1"`, Type: tfvars.StringType},
+				{Name: "replicas", Value: "3", Type: tfvars.NumberType},
+			},
+		},
+		{name: "missing secret", path: "app/missing", wantNotFound: true},
+		{name: "deleted secret", path: "app/deleted", wantNotFound: true},
+		{name: "conversion failure", path: "app/bad", wantErr: "value is not a number"},
+		{name: "key injecting a line", path: "app/inject", wantErr: `key "a = 1\nb": invalid variable name`},
+		{name: "key with a space", path: "app/space", wantErr: `key "x y": invalid variable name`},
+		{name: "invalid mapped name", path: "app/mapped", wantErr: `invalid variable name "not a name"`},
+	}
+
+	b, err := New(backend.Config{"address": server.URL, "keys": keys})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	getter := b.(backend.VariablesGetter)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getter.GetVariables(context.Background(), tt.path)
+			switch {
+			case tt.wantNotFound:
+				if !errors.Is(err, backend.ErrNotFound) {
+					t.Fatalf("GetVariables() error = %v, want ErrNotFound", err)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetVariables() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if strings.Contains(err.Error(), "hunter22") {
+					t.Errorf("error contains the secret value: %v", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("GetVariables() error = %v", err)
+				}
+				for i := range tt.want {
+					tt.want[i].Source = "vault:secret/" + tt.path
+					tt.want[i].Sensitive = true
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("GetVariables() = %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetVariablesForbidden(t *testing.T) {
+	server := newKVServer(t, map[string]map[string]interface{}{"app": {"a": "b"}})
+	t.Setenv("VAULT_TOKEN", "wrong")
+
+	b, err := New(backend.Config{"address": server.URL})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	_, err = b.(backend.VariablesGetter).GetVariables(context.Background(), "app")
+	if err == nil || errors.Is(err, backend.ErrNotFound) || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("GetVariables() error = %v, want a permission error", err)
+	}
+}
+
+func TestAppRoleLogin(t *testing.T) {
+	server := newKVServer(t, map[string]map[string]interface{}{"app": {"a": "value"}})
+	t.Setenv("TIVOR_TEST_ROLE_ID", "role")
+	t.Setenv("TIVOR_TEST_SECRET_ID", "secret")
+
+	b, err := New(backend.Config{
+		"address": server.URL,
+		"auth": map[string]interface{}{
+			"method":        "approle",
+			"role_id_env":   "TIVOR_TEST_ROLE_ID",
+			"secret_id_env": "TIVOR_TEST_SECRET_ID",
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// A canceled context must not be remembered as a login failure
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.GetVarsFile(canceled, "app"); err == nil {
+		t.Fatal("GetVarsFile() with a canceled context succeeded")
+	}
+
+	for i := 0; i < 2; i++ {
+		content, err := b.GetVarsFile(context.Background(), "app")
+		if err != nil {
+			t.Fatalf("GetVarsFile() error = %v", err)
+		}
+		if want := "a = \"value\"\n"; string(content) != want {
+			t.Errorf("GetVarsFile() = %q, want %q", content, want)
+		}
+	}
+	if got := server.logins.Load(); got != 1 {
+		t.Errorf("logged in %d times, want 1", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+
+	tests := []struct {
+		name    string
+		config  backend.Config
+		wantErr string
+	}{
+		{name: "address", config: backend.Config{"address": "https://vault.example.com"}},
+		{name: "missing address", config: backend.Config{}, wantErr: "requires an address"},
+		{name: "unknown auth method", config: backend.Config{"address": "https://vault.example.com", "auth": map[string]interface{}{"method": "ldap"}}, wantErr: "unknown vault auth method"},
+		{name: "unknown key type", config: backend.Config{"address": "https://vault.example.com", "keys": map[string]interface{}{"a": map[string]interface{}{"type": "date"}}}, wantErr: "unknown type"},
+		{name: "invalid timeout", config: backend.Config{"address": "https://vault.example.com", "timeout": "soon"}, wantErr: "invalid timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
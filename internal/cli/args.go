@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// terraformArgFlags holds the extra Terraform arguments accepted by plan and apply.
+type terraformArgFlags struct {
+	targets     []string
+	replaces    []string
+	parallelism int
+	lockTimeout string
+	refresh     bool
+}
+
+// register adds the extra argument flags to the command.
+func (f *terraformArgFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&f.targets, "target", nil, "Resource address to target (can be repeated)")
+	cmd.Flags().StringArrayVar(&f.replaces, "replace", nil, "Resource address to force replacement of (can be repeated)")
+	cmd.Flags().IntVar(&f.parallelism, "parallelism", 0, "Limit the number of concurrent operations")
+	cmd.Flags().StringVar(&f.lockTimeout, "lock-timeout", "", "Duration to retry a state lock (e.g. 30s)")
+	cmd.Flags().BoolVar(&f.refresh, "refresh", true, "Update state prior to checking for differences")
+}
+
+// args converts the flags that were set into Terraform arguments.
+func (f *terraformArgFlags) args(cmd *cobra.Command) []string {
+	var args []string
+	for _, target := range f.targets {
+		args = append(args, fmt.Sprintf("-target=%s", target))
+	}
+	for _, replace := range f.replaces {
+		args = append(args, fmt.Sprintf("-replace=%s", replace))
+	}
+	if cmd.Flags().Changed("parallelism") {
+		args = append(args, fmt.Sprintf("-parallelism=%d", f.parallelism))
+	}
+	if f.lockTimeout != "" {
+		args = append(args, fmt.Sprintf("-lock-timeout=%s", f.lockTimeout))
+	}
+	if cmd.Flags().Changed("refresh") {
+		args = append(args, fmt.Sprintf("-refresh=%t", f.refresh))
+	}
+	return args
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marcy326/tivor/internal/backend/cache"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cachePruneOlderThan time.Duration
+)
+
+// NewCacheCmd creates the cache command.
+func NewCacheCmd() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local cache of remote vars files",
+		Long: `Manages the cache of vars files fetched from remote backends, used by --offline.
+The cache is stored in $TIVOR_CACHE_DIR, or ~/.cache/tivor by default.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List cached vars files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCacheList()
+		},
+	}
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove old entries from the cache",
+		Long: `Removes cache entries fetched before the given age and contents no entry refers to.
+
+Examples:
+  tivor cache prune
+  tivor cache prune --older-than=24h`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCachePrune(cachePruneOlderThan)
+		},
+	}
+	pruneCmd.Flags().DurationVar(&cachePruneOlderThan, "older-than", 30*24*time.Hour, "Remove entries fetched longer ago than this")
+
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove every file tivor stored in the cache",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCacheClear()
+		},
+	}
+
+	cacheCmd.AddCommand(listCmd)
+	cacheCmd.AddCommand(pruneCmd)
+	cacheCmd.AddCommand(clearCmd)
+
+	return cacheCmd
+}
+
+// openCache opens the cache in its default directory.
+func openCache() (*cache.Cache, error) {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.New(dir), nil
+}
+
+// runCacheList performs the actual processing of the cache list command.
+func runCacheList() error {
+	c, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Printf("📂 Cache is empty: %s\n", c.Dir())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tLOCATION\tPATH\tVERSION\tSIZE\tFETCHED")
+	for _, entry := range entries {
+		size := fmt.Sprintf("%d", entry.Size)
+		if entry.Missing {
+			size = "missing"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Type,
+			orDash(entry.Location),
+			entry.Path,
+			orDash(entry.Version),
+			size,
+			entry.FetchedAt.Local().Format(time.DateTime))
+	}
+	return w.Flush()
+}
+
+// runCachePrune performs the actual processing of the cache prune command.
+func runCachePrune(olderThan time.Duration) error {
+	c, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	entries, objects, err := c.Prune(time.Now().Add(-olderThan))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Pruned %d cache entries and %d cached files\n", entries, objects)
+	return nil
+}
+
+// runCacheClear performs the actual processing of the cache clear command.
+func runCacheClear() error {
+	c, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	if err := c.Clear(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Cleared cache: %s\n", c.Dir())
+	return nil
+}
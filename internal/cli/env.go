@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marcy326/tivor/internal/config"
+	"github.com/marcy326/tivor/internal/redact"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	envShowOutput string
+	envShowVars   bool
+)
+
+// envShowResult is the output of env show.
+type envShowResult struct {
+	Environment map[string]interface{} `yaml:"environment" json:"environment"`
+	Origins     map[string][]string    `yaml:"origins" json:"origins"`
+	DeclaredIn  string                 `yaml:"declared_in,omitempty" json:"declared_in,omitempty"`
+	Variables   []envShowVariable      `yaml:"variables,omitempty" json:"variables,omitempty"`
+}
+
+// envShowVariable is a resolved variable in the output of env show --vars.
+type envShowVariable struct {
+	Name string `yaml:"name" json:"name"`
+	// Value is HCL-encoded, or redacted for sensitive variables
+	Value     string `yaml:"value" json:"value"`
+	Source    string `yaml:"source" json:"source"`
+	Sensitive bool   `yaml:"sensitive,omitempty" json:"sensitive,omitempty"`
+}
+
+// NewEnvCmd creates the env command.
+func NewEnvCmd() *cobra.Command {
+	envCmd := &cobra.Command{
+		Use:   "env",
+		Short: "Inspect environments",
+		Long:  "Lists environments and shows how they resolve.",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List environments",
+		Long: `Lists every environment with its parent chain, vars backend, working directory, workspace,
+whether it is protected and its tags.
+
+Examples:
+  tivor env list`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEnvList()
+		},
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show [environment-name]",
+		Short: "Show the resolved configuration of an environment",
+		Long: `Prints the fully resolved configuration of an environment, with inheritance
+and references applied, and where each field comes from. With --vars, also reads
+the vars files and lists every resolved variable with the file or vars block it
+comes from; sensitive values are redacted.
+
+Examples:
+  tivor env show staging
+  tivor env show staging --vars
+  tivor env show production --output=json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEnvShow(args[0], envShowOutput, envShowVars)
+		},
+	}
+	showCmd.Flags().StringVarP(&envShowOutput, "output", "o", "yaml", "Output format (yaml, json)")
+	showCmd.Flags().BoolVar(&envShowVars, "vars", false, "Resolve variables and show where each one comes from")
+
+	envCmd.AddCommand(listCmd)
+	envCmd.AddCommand(showCmd)
+
+	return envCmd
+}
+
+// runEnvList performs the actual processing of the env list command.
+func runEnvList() error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration file not loaded")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tINHERITS\tBACKEND\tWORKING DIR\tWORKSPACE\tPROTECTED\tTAGS")
+
+	for _, e := range cfg.Environments {
+		chain, err := cfg.InheritanceChain(e.Name)
+		if err != nil {
+			return err
+		}
+		env, err := cfg.ResolveEnvironment(e.Name)
+		if err != nil {
+			return fmt.Errorf("failed to resolve environment %s: %w", e.Name, err)
+		}
+
+		protected := "no"
+		if env.Protected {
+			protected = "yes"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			env.Name,
+			orDash(strings.Join(chain[1:], " → ")),
+			env.BackendType(),
+			orDash(env.WorkingDir),
+			orDash(env.Workspace),
+			protected,
+			orDash(strings.Join(env.Tags, ",")))
+	}
+
+	return w.Flush()
+}
+
+// runEnvShow performs the actual processing of the env show command.
+func runEnvShow(envName, output string, showVars bool) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration file not loaded")
+	}
+
+	env, err := cfg.ResolveEnvironment(envName)
+	if err != nil {
+		return fmt.Errorf("failed to resolve environment configuration: %w", err)
+	}
+
+	origins, err := cfg.FieldOrigins(envName)
+	if err != nil {
+		return err
+	}
+
+	// Round-trip through YAML so both formats use the tivor.yaml field names
+	data, err := yaml.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to encode environment: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed to encode environment: %w", err)
+	}
+
+	// Never show the values of sensitive inline vars
+	sensitive, err := config.SensitiveNames(env, "")
+	if err != nil {
+		return err
+	}
+	if vars, ok := fields["vars"].(map[string]interface{}); ok {
+		for name := range vars {
+			if sensitive[name] {
+				vars[name] = redact.Placeholder
+			}
+		}
+	}
+
+	result := envShowResult{
+		Environment: fields,
+		Origins:     origins,
+		DeclaredIn:  env.Source,
+	}
+
+	if showVars {
+		ctx, stop := newSignalContext()
+		defer stop()
+		variables, err := cfg.ResolveVariables(ctx, envName, "")
+		if err != nil {
+			return fmt.Errorf("failed to load variable files: %w", err)
+		}
+		result.Variables = make([]envShowVariable, 0, len(variables))
+		for _, variable := range variables {
+			value := variable.Value
+			if variable.Sensitive {
+				value = redact.Placeholder
+			}
+			result.Variables = append(result.Variables, envShowVariable{
+				Name:      variable.Name,
+				Value:     value,
+				Source:    variable.Source,
+				Sensitive: variable.Sensitive,
+			})
+		}
+	}
+
+	switch output {
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode environment: %w", err)
+		}
+		return encoder.Close()
+	case "json":
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode environment: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	default:
+		return fmt.Errorf("invalid output format: %s (expected yaml or json)", output)
+	}
+}
+
+// orDash returns s, or "-" if it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcy326/tivor/internal/config"
+	"github.com/marcy326/tivor/internal/terraform"
+)
+
+// newExecutor creates a Terraform executor configured for the resolved environment.
+func newExecutor(env *config.Environment, workingDir, varsFile string) (*terraform.Executor, error) {
+	executor := terraform.NewExecutor(workingDir, varsFile)
+	executor.SetWorkspace(env.Workspace)
+	executor.SetGracePeriod(gracePeriod)
+
+	if env.Execution == nil {
+		return executor, nil
+	}
+
+	if t := env.Execution.Timeouts; t != nil {
+		executor.SetTimeout("init", t.Init)
+		executor.SetTimeout("plan", t.Plan)
+		executor.SetTimeout("apply", t.Apply)
+	}
+
+	if r := env.Execution.Retry; r != nil {
+		policy, err := terraform.NewRetryPolicy(r.MaxAttempts, r.InitialBackoff, r.MaxBackoff, r.Patterns)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry policy: %w", err)
+		}
+		executor.SetRetryPolicy(policy)
+	}
+
+	return executor, nil
+}
+
+// printAttemptSummary prints the commands that needed more than one attempt.
+func printAttemptSummary(executor *terraform.Executor) {
+	var commands []string
+	counts := make(map[string]int)
+	for _, attempt := range executor.Attempts() {
+		if _, ok := counts[attempt.Command]; !ok {
+			commands = append(commands, attempt.Command)
+		}
+		counts[attempt.Command] = attempt.Number
+	}
+
+	var parts []string
+	for _, command := range commands {
+		if counts[command] > 1 {
+			parts = append(parts, fmt.Sprintf("%s=%d", command, counts[command]))
+		}
+	}
+
+	if len(parts) > 0 {
+		fmt.Printf("🔁 Attempts: %s\n", strings.Join(parts, ", "))
+	}
+}
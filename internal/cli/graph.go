@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcy326/tivor/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphFormat    string
+	graphHighlight string
+)
+
+// graphNode is an environment, or the defaults block, in the inheritance graph
+type graphNode struct {
+	ID        string
+	Name      string
+	VarsFiles []string
+	Children  []*graphNode
+	Lineage   bool
+	Defaults  bool
+}
+
+// NewGraphCmd creates the graph command.
+func NewGraphCmd() *cobra.Command {
+	graphCmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Show the environment inheritance graph",
+		Long: `Prints the environment inheritance graph with the vars files each environment adds.
+
+Formats:
+  tree     ASCII tree (default)
+  dot      Graphviz DOT
+  mermaid  Mermaid flowchart
+
+Examples:
+  tivor graph
+  tivor graph --highlight=production
+  tivor graph --format=dot | dot -Tsvg > environments.svg`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGraph(graphFormat, graphHighlight)
+		},
+	}
+
+	graphCmd.Flags().StringVarP(&graphFormat, "format", "f", "tree", "Output format (tree, dot, mermaid)")
+	graphCmd.Flags().StringVar(&graphHighlight, "highlight", "", "Highlight the lineage of an environment")
+
+	return graphCmd
+}
+
+// runGraph performs the actual processing of the graph command.
+func runGraph(format, highlight string) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration file not loaded")
+	}
+
+	roots, nodes, err := buildGraph(cfg, highlight)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "tree":
+		fmt.Print(renderTree(roots))
+	case "dot":
+		fmt.Print(renderDOT(nodes))
+	case "mermaid":
+		fmt.Print(renderMermaid(nodes))
+	default:
+		return fmt.Errorf("invalid graph format: %s (expected tree, dot or mermaid)", format)
+	}
+
+	return nil
+}
+
+// buildGraph returns the root nodes of the inheritance graph and every node in configuration order.
+// If the defaults add vars files, they form the single root the environments hang off.
+func buildGraph(cfg *config.Config, highlight string) ([]*graphNode, []*graphNode, error) {
+	lineage := make(map[string]bool)
+	if highlight != "" {
+		chain, err := cfg.InheritanceChain(highlight)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, name := range chain {
+			lineage[name] = true
+		}
+	}
+
+	var nodes []*graphNode
+	byName := make(map[string]*graphNode)
+	for i, env := range cfg.Environments {
+		node := &graphNode{
+			ID:        fmt.Sprintf("env%d", i),
+			Name:      env.Name,
+			VarsFiles: varsFileStrings(env.VarsFiles),
+			Lineage:   lineage[env.Name],
+		}
+		nodes = append(nodes, node)
+		byName[env.Name] = node
+	}
+
+	var roots []*graphNode
+	for i, env := range cfg.Environments {
+		if env.Inherits == "" {
+			roots = append(roots, nodes[i])
+			continue
+		}
+		parent, ok := byName[env.Inherits]
+		if !ok {
+			return nil, nil, fmt.Errorf("environment %s inherits from non-existent environment %s", env.Name, env.Inherits)
+		}
+		parent.Children = append(parent.Children, nodes[i])
+	}
+
+	if cfg.Defaults != nil && len(cfg.Defaults.VarsFiles) > 0 {
+		defaults := &graphNode{
+			ID:        "defaults",
+			Name:      config.DefaultsOrigin,
+			VarsFiles: varsFileStrings(cfg.Defaults.VarsFiles),
+			Children:  roots,
+			Lineage:   highlight != "",
+			Defaults:  true,
+		}
+		roots = []*graphNode{defaults}
+		nodes = append([]*graphNode{defaults}, nodes...)
+	}
+
+	return roots, nodes, nil
+}
+
+// renderTree renders the graph as an ASCII tree, marking the highlighted lineage with "*"
+func renderTree(roots []*graphNode) string {
+	var b strings.Builder
+
+	var walk func(node *graphNode, prefix, branch, indent string)
+	walk = func(node *graphNode, prefix, branch, indent string) {
+		label := node.Name
+		if node.Lineage {
+			label += " *"
+		}
+		if len(node.VarsFiles) > 0 {
+			label += "  [" + strings.Join(node.VarsFiles, ", ") + "]"
+		}
+		fmt.Fprintf(&b, "%s%s%s\n", prefix, branch, label)
+
+		for i, child := range node.Children {
+			if i == len(node.Children)-1 {
+				walk(child, prefix+indent, "└── ", "    ")
+			} else {
+				walk(child, prefix+indent, "├── ", "│   ")
+			}
+		}
+	}
+
+	for _, root := range roots {
+		walk(root, "", "", "")
+	}
+
+	return b.String()
+}
+
+// renderDOT renders the graph in Graphviz DOT format
+func renderDOT(nodes []*graphNode) string {
+	var b strings.Builder
+
+	b.WriteString("digraph environments {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for _, node := range nodes {
+		label := strings.Join(append([]string{node.Name}, node.VarsFiles...), "\n")
+		var attrs []string
+		attrs = append(attrs, fmt.Sprintf("label=%s", dotQuote(label)))
+		if node.Defaults {
+			attrs = append(attrs, "style=dashed")
+		}
+		if node.Lineage {
+			attrs = append(attrs, "color=red", "penwidth=2")
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", node.ID, strings.Join(attrs, ", "))
+	}
+
+	for _, node := range nodes {
+		for _, child := range node.Children {
+			var attrs []string
+			if node.Defaults {
+				attrs = append(attrs, "style=dashed")
+			}
+			if node.Lineage && child.Lineage {
+				attrs = append(attrs, "color=red", "penwidth=2")
+			}
+			if len(attrs) > 0 {
+				fmt.Fprintf(&b, "  %s -> %s [%s];\n", node.ID, child.ID, strings.Join(attrs, ", "))
+			} else {
+				fmt.Fprintf(&b, "  %s -> %s;\n", node.ID, child.ID)
+			}
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// renderMermaid renders the graph as a Mermaid flowchart
+func renderMermaid(nodes []*graphNode) string {
+	var b strings.Builder
+
+	b.WriteString("flowchart LR\n")
+
+	var lineage []string
+	for _, node := range nodes {
+		label := strings.Join(append([]string{node.Name}, node.VarsFiles...), "<br/>")
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", node.ID, strings.ReplaceAll(label, `"`, "#quot;"))
+		if node.Lineage {
+			lineage = append(lineage, node.ID)
+		}
+	}
+
+	for _, node := range nodes {
+		arrow := "-->"
+		if node.Defaults {
+			arrow = "-.->"
+		}
+		for _, child := range node.Children {
+			fmt.Fprintf(&b, "  %s %s %s\n", node.ID, arrow, child.ID)
+		}
+	}
+
+	if len(lineage) > 0 {
+		b.WriteString("  classDef lineage stroke:#d00,stroke-width:3px\n")
+		fmt.Fprintf(&b, "  class %s lineage\n", strings.Join(lineage, ","))
+	}
+
+	return b.String()
+}
+
+// varsFileStrings returns vars_files entries as written in tivor.yaml
+func varsFileStrings(varsFiles []config.VarsFile) []string {
+	result := make([]string, len(varsFiles))
+	for i, varsFile := range varsFiles {
+		result[i] = varsFile.String()
+	}
+	return result
+}
+
+// dotQuote quotes s as a DOT string
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -44,7 +44,7 @@ func runInit() error {
 	template := `# tivor.yaml - Terraform Infrastructure Variable Orchestrator Configuration File
 
 # Configuration file version
-version: "1.0"
+version: "1.1"
 
 # Default settings for the entire project
 defaults:
@@ -65,7 +65,7 @@ environments:
   - name: dev
     vars_files:
       - "terraform/variables/dev.tfvars"
-    backend:
+    vars_backend:
       type: local
 
   # --- Staging Environment ---
@@ -74,7 +74,7 @@ environments:
     inherits: dev
     vars_files:
       - "terraform/variables/staging.tfvars"
-    backend:
+    vars_backend:
       type: local
 
   # --- Production Environment ---
@@ -84,7 +84,7 @@ environments:
     vars_files:
       - "terraform/variables/production.tfvars"
     # Example of using remote backend for production
-    backend:
+    vars_backend:
       type: s3
       config:
         bucket: "your-tfstate-bucket"
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/marcy326/tivor/internal/config"
+	"github.com/marcy326/tivor/internal/tfvars"
+	"github.com/spf13/cobra"
+)
+
+// NewLockCmd creates the lock command.
+func NewLockCmd() *cobra.Command {
+	lockCmd := &cobra.Command{
+		Use:   "lock [environment-name...]",
+		Short: "Record the exact vars files of environments in tivor.lock",
+		Long: `Resolves every vars file of the given environments (all environments by default)
+and records its backend, version and SHA-256 checksum, along with the checksum of
+the rendered tfvars, in tivor.lock next to the configuration file.
+plan and apply --locked fail if the inputs no longer match the lock.
+
+Examples:
+  tivor lock
+  tivor lock production`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLock(args)
+		},
+	}
+
+	return lockCmd
+}
+
+// lockFilePath returns the path of the lock file next to the configuration file.
+func lockFilePath() string {
+	return filepath.Join(filepath.Dir(configPath), config.LockFileName)
+}
+
+// runLock performs the actual processing of the lock command.
+func runLock(envNames []string) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration file not loaded")
+	}
+
+	path := lockFilePath()
+	lock := config.NewLock()
+	if len(envNames) == 0 {
+		// Locking every environment also drops environments that were removed
+		for _, env := range cfg.Environments {
+			envNames = append(envNames, env.Name)
+		}
+	} else {
+		existing, err := config.ReadLock(path)
+		switch {
+		case err == nil:
+			lock = existing
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		}
+		for _, envName := range envNames {
+			if _, err := cfg.GetEnvironment(envName); err != nil {
+				return err
+			}
+		}
+	}
+
+	ctx, stop := newSignalContext()
+	defer stop()
+	for _, envName := range envNames {
+		envLock, _, err := cfg.LockVarsFiles(ctx, envName, "")
+		if err != nil {
+			return fmt.Errorf("environment %s: %w", envName, err)
+		}
+		lock.Environments[envName] = envLock
+		slog.Info("Environment locked", "environment", envName, "vars_files", len(envLock.VarsFiles))
+	}
+
+	if err := lock.Write(path); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Locked %d environment(s) in %s\n", len(envNames), path)
+	return nil
+}
+
+// resolveLockedVariables resolves the variables of an environment and verifies its vars files
+// against the lock file. workingDir is passed on to LockVarsFiles.
+func resolveLockedVariables(ctx context.Context, cfg *config.Config, envName, workingDir string) ([]tfvars.Variable, error) {
+	path := lockFilePath()
+	lock, err := config.ReadLock(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("--locked requires %s; run tivor lock first", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	expected, ok := lock.Environments[envName]
+	if !ok {
+		return nil, fmt.Errorf("environment %s is not in %s; run tivor lock %s", envName, path, envName)
+	}
+
+	actual, variables, err := cfg.LockVarsFiles(ctx, envName, workingDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := expected.Verify(actual); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Vars files match the lock file", "environment", envName, "lock_file", path)
+	return variables, nil
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/marcy326/tivor/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateDryRun bool
+)
+
+// NewMigrateCmd creates the migrate command.
+func NewMigrateCmd() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade tivor.yaml to the latest config version",
+		Long: `Rewrites the configuration file and every file it includes to the latest
+config version, preserving comments and key order.
+
+Examples:
+  tivor migrate
+  tivor migrate --dry-run
+  tivor migrate --config=infra/tivor.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrate(configPath, migrateDryRun)
+		},
+	}
+
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Print the migrated files instead of writing them")
+
+	return migrateCmd
+}
+
+// runMigrate performs the actual processing of the migrate command.
+func runMigrate(path string, dryRun bool) error {
+	slog.Info("Starting config migration", "path", path, "target_version", config.LatestVersion)
+
+	files, err := config.Migrate(path)
+	if err != nil {
+		return fmt.Errorf("failed to migrate configuration: %w", err)
+	}
+
+	changed := 0
+	for _, file := range files {
+		if !file.Changed {
+			fmt.Printf("✔️  %s is already at version %s\n", file.Path, config.LatestVersion)
+			continue
+		}
+		changed++
+
+		for _, d := range file.Deprecations {
+			fmt.Printf("   %s:%d:%d: %s\n", file.Path, d.Line, d.Column, d.Message)
+		}
+
+		if dryRun {
+			fmt.Printf("--- %s (%s → %s)\n", file.Path, file.FromVersion, config.LatestVersion)
+			fmt.Print(string(file.Content))
+			continue
+		}
+
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return fmt.Errorf("failed to stat config file (%s): %w", file.Path, err)
+		}
+		if err := os.WriteFile(file.Path, file.Content, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write config file (%s): %w", file.Path, err)
+		}
+		fmt.Printf("✅ Migrated %s from version %s to %s\n", file.Path, file.FromVersion, config.LatestVersion)
+	}
+
+	if changed == 0 {
+		fmt.Println("Nothing to migrate.")
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/marcy326/tivor/internal/config"
+	"github.com/marcy326/tivor/internal/hooks"
+	"github.com/marcy326/tivor/internal/terraform"
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+// runTerraform runs the shared plan/apply pipeline for the given operation.
+// If locked is set, the vars files must match the lock file.
+func runTerraform(operation, envName, workingDir string, extraArgs []string, locked bool) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration file not loaded")
+	}
+
+	// 1. Resolve environment configuration (including inheritance)
+	env, err := cfg.ResolveEnvironment(envName)
+	if err != nil {
+		return fmt.Errorf("failed to resolve environment configuration: %w", err)
+	}
+
+	if workingDir == "" {
+		workingDir = env.WorkingDir
+	}
+	if workingDir == "" {
+		workingDir = "."
+	}
+
+	slog.Info("Environment configuration loaded",
+		"environment", env.Name,
+		"vars_files", env.VarsFiles,
+		"workspace", env.Workspace,
+		"working_dir", workingDir,
+		"backend_type", env.BackendType())
+
+	// 2. Load variable files
+	slog.Info("Loading variable files", "files", env.VarsFiles)
+	ctx, stop := newSignalContext()
+	defer stop()
+	var variables []tfvars.Variable
+	if locked {
+		variables, err = resolveLockedVariables(ctx, cfg, envName, workingDir)
+	} else {
+		variables, err = cfg.ResolveVariables(ctx, envName, workingDir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load variable files: %w", err)
+	}
+	combinedVars := []byte(tfvars.GenerateTfvars(variables, envName))
+	slog.Info("Variable files loaded successfully", "variables", len(variables), "total_size", len(combinedVars))
+
+	// 3. Decrypt secrets (not implemented yet)
+	if cfg.Secrets != nil && cfg.Secrets.Engine == "sops" {
+		slog.Info("SOPS secret decryption will be implemented in future", "engine", cfg.Secrets.Engine)
+		// TODO: Implement SOPS decryption processing
+	}
+
+	// 4. Create the temporary directory and, unless variables are injected otherwise, the variable file
+	tmpDir, err := os.MkdirTemp("", "tivor-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			slog.Warn("Failed to cleanup temporary directory", "dir", tmpDir, "error", err)
+		}
+	}()
+
+	var tmpVarsFile string
+	injection := env.Execution.VarsInjectionMode()
+	if injection == terraform.VarsInjectionFile {
+		tmpVarsFile = filepath.Join(tmpDir, fmt.Sprintf("%s.tfvars", envName))
+		if err := os.WriteFile(tmpVarsFile, combinedVars, 0600); err != nil {
+			return fmt.Errorf("failed to write temporary vars file: %w", err)
+		}
+		slog.Info("Temporary variable file created", "path", tmpVarsFile)
+	}
+
+	// 5. Execute terraform
+	executor, err := newExecutor(env, workingDir, tmpVarsFile)
+	if err != nil {
+		return fmt.Errorf("failed to configure terraform executor: %w", err)
+	}
+	defer printAttemptSummary(executor)
+
+	switch injection {
+	case terraform.VarsInjectionEnv:
+		executor.SetEnvVariables(variables)
+		slog.Info("Passing variables as TF_VAR_ environment variables", "variables", len(variables))
+	case terraform.VarsInjectionPipe:
+		executor.SetVarsPipe(combinedVars)
+		slog.Info("Passing variables through an inherited pipe")
+	}
+
+	runner := hooks.NewRunner(env.Hooks, map[string]string{
+		hooks.EnvEnvironment: envName,
+		hooks.EnvVarsFile:    tmpVarsFile,
+		hooks.EnvWorkingDir:  workingDir,
+	})
+
+	// A saved plan is only needed when post_plan hooks want to inspect it
+	if operation == "plan" && len(env.Hooks.For(config.HookPostPlan)) > 0 {
+		executor.SetPlanFile(filepath.Join(tmpDir, fmt.Sprintf("%s.tfplan", envName)))
+	}
+
+	// step runs fn and, on failure, the on_failure hooks
+	step := func(name string, fn func() error) error {
+		if err := fn(); err != nil {
+			runner.Set(hooks.EnvFailedStep, name)
+			runner.Set(hooks.EnvError, err.Error())
+			if hookErr := runOnFailure(ctx, runner); hookErr != nil {
+				slog.Error("on_failure hook failed", "error", hookErr)
+			}
+			return err
+		}
+		return nil
+	}
+
+	// Validate working directory
+	if err := step("validate", func() error {
+		if err := executor.ValidateWorkingDirectory(); err != nil {
+			return fmt.Errorf("terraform working directory validation failed: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Initialize terraform if needed
+	if err := step(config.HookPreInit, func() error { return runner.Run(ctx, config.HookPreInit) }); err != nil {
+		return err
+	}
+	if err := step("init", func() error {
+		slog.Info("Initializing Terraform")
+		if err := executor.Init(ctx); err != nil {
+			return fmt.Errorf("terraform init failed: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Select terraform workspace if configured
+	if err := step("workspace", func() error {
+		if err := executor.SelectWorkspace(ctx); err != nil {
+			return fmt.Errorf("terraform workspace selection failed: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	preHook, postHook := config.HookPrePlan, config.HookPostPlan
+	if operation == "apply" {
+		preHook, postHook = config.HookPreApply, config.HookPostApply
+	}
+
+	if err := step(preHook, func() error { return runner.Run(ctx, preHook) }); err != nil {
+		return err
+	}
+
+	if err := step(operation, func() error {
+		switch operation {
+		case "plan":
+			// Execute terraform plan (configured arguments first, command line last)
+			planArgs := append(append([]string{}, env.PlanArgs...), extraArgs...)
+			slog.Info("Executing Terraform plan", "args", planArgs)
+			if err := executor.Plan(ctx, planArgs...); err != nil {
+				return fmt.Errorf("terraform plan failed: %w", err)
+			}
+		case "apply":
+			// Make sure the apply cannot land in another environment's state
+			if err := executor.VerifyWorkspace(ctx); err != nil {
+				return fmt.Errorf("terraform workspace verification failed: %w", err)
+			}
+
+			// Execute terraform apply (configured arguments first, command line last)
+			applyArgs := append(append([]string{}, env.ApplyArgs...), extraArgs...)
+			slog.Info("Executing Terraform apply", "args", applyArgs)
+			if err := executor.Apply(ctx, applyArgs...); err != nil {
+				return fmt.Errorf("terraform apply failed: %w", err)
+			}
+		default:
+			return fmt.Errorf("unsupported operation: %s", operation)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := step(postHook, func() error {
+		if operation == "plan" && len(env.Hooks.For(config.HookPostPlan)) > 0 {
+			planJSON, err := executor.ShowPlanJSON(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to render plan as JSON: %w", err)
+			}
+			planJSONFile := filepath.Join(tmpDir, fmt.Sprintf("%s.plan.json", envName))
+			if err := os.WriteFile(planJSONFile, planJSON, 0600); err != nil {
+				return fmt.Errorf("failed to write plan JSON: %w", err)
+			}
+			runner.Set(hooks.EnvPlanJSON, planJSONFile)
+		}
+		return runner.Run(ctx, postHook)
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Terraform %s completed successfully for environment: %s\n", operation, envName)
+	switch injection {
+	case terraform.VarsInjectionEnv:
+		fmt.Printf("📁 Variables: TF_VAR_ environment variables\n")
+	case terraform.VarsInjectionPipe:
+		fmt.Printf("📁 Variables: inherited pipe (/dev/fd/3)\n")
+	default:
+		fmt.Printf("📁 Variables file: %s\n", tmpVarsFile)
+	}
+	fmt.Printf("📂 Working directory: %s\n", workingDir)
+
+	return nil
+}
+
+// runOnFailure runs the on_failure hooks. After an interrupt they still run, but are
+// stopped once the grace period is over.
+func runOnFailure(ctx context.Context, runner *hooks.Runner) error {
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), gracePeriod)
+		defer cancel()
+	}
+	return runner.Run(ctx, config.HookOnFailure)
+}
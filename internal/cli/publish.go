@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+
+	"github.com/marcy326/tivor/internal/tfvars"
+	"github.com/spf13/cobra"
+)
+
+var (
+	publishPath             string
+	publishIncludeSensitive bool
+)
+
+// NewPublishCmd creates the publish command.
+func NewPublishCmd() *cobra.Command {
+	publishCmd := &cobra.Command{
+		Use:   "publish [environment-name]",
+		Short: "Upload the rendered tfvars of an environment to its vars backend",
+		Long: `Resolves the variables of an environment exactly as plan does and stores the
+rendered tfvars in the environment's vars backend, so other tools can consume them.
+Environments with sensitive variables are only published with --include-sensitive.
+
+Examples:
+  tivor publish staging
+  tivor publish production --path=rendered/production.tfvars`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPublish(args[0], publishPath, publishIncludeSensitive)
+		},
+	}
+
+	publishCmd.Flags().StringVarP(&publishPath, "path", "p", "", "Path in the vars backend (defaults to published/<environment>.tfvars)")
+	publishCmd.Flags().BoolVar(&publishIncludeSensitive, "include-sensitive", false, "Publish even if some variables are sensitive")
+
+	return publishCmd
+}
+
+// runPublish performs the actual processing of the publish command.
+func runPublish(envName, targetPath string, includeSensitive bool) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration file not loaded")
+	}
+
+	env, err := cfg.ResolveEnvironment(envName)
+	if err != nil {
+		return fmt.Errorf("failed to resolve environment configuration: %w", err)
+	}
+	if targetPath == "" {
+		targetPath = path.Join("published", envName+".tfvars")
+	}
+
+	ctx, stop := newSignalContext()
+	defer stop()
+	variables, err := cfg.ResolveVariables(ctx, envName, "")
+	if err != nil {
+		return fmt.Errorf("failed to load variable files: %w", err)
+	}
+
+	if !includeSensitive {
+		for _, variable := range variables {
+			if variable.Sensitive {
+				return fmt.Errorf("variable %s of environment %s is sensitive; use --include-sensitive to publish it", variable.Name, envName)
+			}
+		}
+	}
+
+	content := []byte(tfvars.GenerateTfvars(variables, envName))
+	if err := cfg.PublishVarsFile(ctx, env, targetPath, content); err != nil {
+		return err
+	}
+	slog.Info("Published rendered vars", "environment", envName, "backend_type", env.BackendType(), "path", targetPath, "size", len(content))
+
+	fmt.Printf("✅ Published %d variable(s) of %s to %s\n", len(variables), envName, targetPath)
+	return nil
+}
@@ -1,18 +1,25 @@
 package cli
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
+	"github.com/marcy326/tivor/internal/backend/cache"
 	"github.com/marcy326/tivor/internal/config"
+	"github.com/marcy326/tivor/internal/redact"
+	"github.com/marcy326/tivor/internal/terraform"
 
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Global flags
-	configPath string
-	logLevel   string
+	configPath  string
+	logLevel    string
+	gracePeriod time.Duration
+	offline     bool
 
 	// Global variables
 	globalConfig *config.Config
@@ -30,13 +37,16 @@ It adopts a GitOps-First approach and manages all environment configurations
 from a single tivor.yaml configuration file.`,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			setupLogging()
-			// Skip config loading for commands that don't need it
-			skipConfigCommands := []string{"version", "init", "sops"}
+			// Skip config loading for commands (and their subcommands) that don't need it.
+			// validate loads the configuration itself to report load errors as findings.
+			skipConfigCommands := []string{"version", "init", "sops", "schema", "migrate", "cache", "validate"}
 			shouldSkip := false
-			for _, cmdName := range skipConfigCommands {
-				if cmd.Name() == cmdName {
-					shouldSkip = true
-					break
+			for c := cmd; c != nil && !shouldSkip; c = c.Parent() {
+				for _, cmdName := range skipConfigCommands {
+					if c.Name() == cmdName {
+						shouldSkip = true
+						break
+					}
 				}
 			}
 			if !shouldSkip {
@@ -48,6 +58,8 @@ from a single tivor.yaml configuration file.`,
 	// Define global flags
 	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "tivor.yaml", "Path to configuration file")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Read remote vars files only from the local cache")
+	rootCmd.PersistentFlags().DurationVar(&gracePeriod, "grace-period", terraform.DefaultGracePeriod, "Time Terraform may take to exit after an interrupt before it is killed")
 
 	// Add subcommands
 	rootCmd.AddCommand(NewVersionCmd())
@@ -55,6 +67,14 @@ from a single tivor.yaml configuration file.`,
 	rootCmd.AddCommand(NewPlanCmd())
 	rootCmd.AddCommand(NewApplyCmd())
 	rootCmd.AddCommand(NewSopsCmd())
+	rootCmd.AddCommand(NewValidateCmd())
+	rootCmd.AddCommand(NewEnvCmd())
+	rootCmd.AddCommand(NewGraphCmd())
+	rootCmd.AddCommand(NewSchemaCmd())
+	rootCmd.AddCommand(NewMigrateCmd())
+	rootCmd.AddCommand(NewCacheCmd())
+	rootCmd.AddCommand(NewPublishCmd())
+	rootCmd.AddCommand(NewLockCmd())
 
 	return rootCmd
 }
@@ -79,20 +99,44 @@ func setupLogging() {
 		Level: level,
 	}
 	handler := slog.NewTextHandler(os.Stderr, opts)
-	logger := slog.New(handler)
+	// Values of sensitive variables never appear in logs
+	logger := slog.New(redact.NewHandler(handler, redact.Default()))
 	slog.SetDefault(logger)
 }
 
-// loadConfig loads the configuration file.
+// loadConfig loads the configuration file, exiting on errors.
 func loadConfig() {
 	var err error
-	globalConfig, err = config.LoadConfig(configPath)
+	globalConfig, err = openConfig()
 	if err != nil {
 		slog.Error("Failed to load configuration file", "error", err)
 		os.Exit(1)
 	}
+}
+
+// openConfig loads the configuration file and sets up the vars cache.
+func openConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
 
-	slog.Info("Configuration file loaded", "path", configPath, "version", globalConfig.Version)
+	slog.Info("Configuration file loaded", "path", configPath, "version", cfg.Version)
+
+	// Remote vars files are read through the local cache
+	cacheDir, err := cache.DefaultDir()
+	if err != nil {
+		if offline {
+			return nil, fmt.Errorf("offline mode requires a cache directory: %w", err)
+		}
+		slog.Debug("Vars cache disabled", "error", err)
+		return cfg, nil
+	}
+	cfg.SetCache(cache.New(cacheDir), offline)
+	if offline {
+		slog.Info("Offline mode: remote vars files are read from the cache", "cache_dir", cacheDir)
+	}
+	return cfg, nil
 }
 
 // GetConfig returns the loaded configuration.
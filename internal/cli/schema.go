@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/marcy326/tivor/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	schemaOutput string
+)
+
+// NewSchemaCmd creates the schema command.
+func NewSchemaCmd() *cobra.Command {
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of tivor.yaml",
+		Long: `Prints a JSON Schema describing tivor.yaml, for validation and completion in editors.
+
+Examples:
+  tivor schema
+  tivor schema --output=tivor.schema.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSchema(schemaOutput)
+		},
+	}
+
+	schemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "", "Write the schema to a file instead of stdout")
+
+	return schemaCmd
+}
+
+// runSchema performs the actual processing of the schema command.
+func runSchema(output string) error {
+	schema, err := config.JSONSchema()
+	if err != nil {
+		return fmt.Errorf("failed to generate schema: %w", err)
+	}
+	schema = append(schema, '\n')
+
+	if output == "" {
+		fmt.Print(string(schema))
+		return nil
+	}
+
+	if err := os.WriteFile(output, schema, 0644); err != nil {
+		return fmt.Errorf("failed to write schema file: %w", err)
+	}
+	fmt.Printf("✅ Wrote JSON Schema to %s\n", output)
+
+	return nil
+}
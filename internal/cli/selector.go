@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/marcy326/tivor/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// environmentSelection holds the flags that select environments by tag.
+type environmentSelection struct {
+	selector string
+	dryRun   bool
+}
+
+// register adds the selection flags to the command.
+func (s *environmentSelection) register(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&s.selector, "selector", "l", "", "Select environments by tags (e.g. 'tier=prod,region!=us')")
+	cmd.Flags().BoolVar(&s.dryRun, "dry-run", false, "Only list the selected environments")
+}
+
+// environments returns the environments named in args, or those matching the selector.
+func (s *environmentSelection) environments(cfg *config.Config, args []string) ([]string, error) {
+	if s.selector == "" {
+		return args, nil
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("specify environment names or --selector, not both")
+	}
+
+	selector, err := config.ParseSelector(s.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := cfg.SelectEnvironments(selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no environments match selector %q", s.selector)
+	}
+
+	slog.Info("Environments selected", "selector", s.selector, "environments", names)
+	return names, nil
+}
+
+// printSelection lists the selected environments for --dry-run.
+func printSelection(names []string) {
+	fmt.Printf("📋 %d environment(s) selected:\n", len(names))
+	for _, name := range names {
+		fmt.Printf("  - %s\n", name)
+	}
+}
+
+// runEach runs fn for every environment in order, stopping at the first failure.
+func runEach(names []string, fn func(envName string) error) error {
+	for _, name := range names {
+		if err := fn(name); err != nil {
+			if len(names) > 1 {
+				return fmt.Errorf("environment %s: %w", name, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
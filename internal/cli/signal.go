@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// newSignalContext returns a context that is cancelled on SIGINT or SIGTERM.
+// Cancellation interrupts the running Terraform command gracefully instead of
+// terminating tivor, so deferred cleanup such as temp file removal still runs.
+// Only the first signal is caught: a second one terminates tivor immediately.
+func newSignalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			slog.Warn("Received signal, stopping Terraform gracefully; repeat it to exit immediately", "signal", sig.String(), "grace_period", gracePeriod)
+			signal.Stop(signals)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
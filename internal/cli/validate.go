@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/marcy326/tivor/internal/config"
+	"github.com/marcy326/tivor/internal/terraform"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateAll        bool
+	validateTerraform  bool
+	validateWorkingDir string
+	validateOutput     string
+	validateSelection  environmentSelection
+)
+
+// validateReport is the JSON output of the validate command.
+type validateReport struct {
+	Environments []string         `json:"environments"`
+	Findings     []config.Finding `json:"findings"`
+	Errors       int              `json:"errors"`
+	Warnings     int              `json:"warnings"`
+}
+
+// NewValidateCmd creates the validate command.
+func NewValidateCmd() *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:   "validate [environment-name...]",
+		Short: "Check configuration, vars files and secrets without running Terraform",
+		Long: `Resolves the given environments, fetches and parses every vars file, and checks
+backend and secrets configuration. All findings are reported with a severity.
+Optionally runs terraform validate once in each distinct working directory of the
+environments: --working-dir if given, or else each environment's working_dir.
+
+Examples:
+  tivor validate staging production
+  tivor validate --all
+  tivor validate --selector='tier=prod'
+  tivor validate --all --terraform --working-dir=./infrastructure
+  tivor validate --all --output=json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Findings are the result; usage help would only bury them
+			cmd.SilenceUsage = true
+			return runValidate(args, validateAll, &validateSelection, validateTerraform, validateWorkingDir, validateOutput)
+		},
+	}
+
+	validateCmd.Flags().BoolVar(&validateAll, "all", false, "Validate every environment")
+	validateSelection.register(validateCmd)
+	validateCmd.Flags().BoolVar(&validateTerraform, "terraform", false, "Also run terraform validate in the working directories")
+	validateCmd.Flags().StringVarP(&validateWorkingDir, "working-dir", "w", "", "Terraform working directory (defaults to each environment's working_dir, or .)")
+	validateCmd.Flags().StringVarP(&validateOutput, "output", "o", "text", "Output format (text, json)")
+
+	return validateCmd
+}
+
+// runValidate performs the actual processing of the validate command.
+func runValidate(envNames []string, all bool, selection *environmentSelection, runTerraformValidate bool, workingDir, output string) error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("invalid output format: %s (expected text or json)", output)
+	}
+
+	// A configuration that cannot be loaded is reported like any other finding
+	cfg, err := openConfig()
+	if err != nil {
+		slog.Debug("Failed to load configuration file", "error", err)
+		finding := config.Finding{Severity: config.SeverityError, Subject: "config", Message: err.Error()}
+		return reportFindings(envNames, []config.Finding{finding}, output)
+	}
+	globalConfig = cfg
+
+	if all {
+		if len(envNames) > 0 || selection.selector != "" {
+			return fmt.Errorf("specify environment names, --selector or --all, not several")
+		}
+		for _, env := range cfg.Environments {
+			envNames = append(envNames, env.Name)
+		}
+	}
+	envNames, err = selection.environments(cfg, envNames)
+	if err != nil {
+		return err
+	}
+	if len(envNames) == 0 {
+		return fmt.Errorf("please specify environment names, --selector or --all")
+	}
+	if selection.dryRun {
+		printSelection(envNames)
+		return nil
+	}
+
+	slog.Info("Starting validation", "environments", envNames)
+	ctx, stop := newSignalContext()
+	defer stop()
+
+	findings := cfg.CheckSecrets()
+	for _, envName := range envNames {
+		findings = append(findings, cfg.CheckEnvironment(ctx, envName)...)
+	}
+
+	if runTerraformValidate {
+		findings = append(findings, checkTerraformDirs(ctx, cfg, envNames, workingDir)...)
+	}
+
+	return reportFindings(envNames, findings, output)
+}
+
+// reportFindings prints the findings in the output format and fails if any is an error.
+func reportFindings(envNames []string, findings []config.Finding, output string) error {
+	report := validateReport{Environments: envNames, Findings: findings}
+	if report.Environments == nil {
+		report.Environments = []string{}
+	}
+	if report.Findings == nil {
+		report.Findings = []config.Finding{}
+	}
+	for _, f := range findings {
+		switch f.Severity {
+		case config.SeverityError:
+			report.Errors++
+		case config.SeverityWarning:
+			report.Warnings++
+		}
+	}
+
+	if output == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printFindings(report)
+	}
+
+	if report.Errors > 0 {
+		return fmt.Errorf("validation failed with %d error(s)", report.Errors)
+	}
+	return nil
+}
+
+// checkTerraformDirs runs terraform validate once in each distinct working directory of the
+// environments, the workingDir override or their working_dir, and reports the findings of a
+// directory for every environment using it.
+func checkTerraformDirs(ctx context.Context, cfg *config.Config, envNames []string, workingDir string) []config.Finding {
+	var dirs []string
+	dirEnvs := make(map[string][]string)
+	for _, envName := range envNames {
+		dir := workingDir
+		if dir == "" {
+			env, err := cfg.ResolveEnvironment(envName)
+			if err != nil {
+				// Already reported by CheckEnvironment
+				continue
+			}
+			dir = env.WorkingDir
+		}
+		if dir == "" {
+			dir = "."
+		}
+		if _, ok := dirEnvs[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		dirEnvs[dir] = append(dirEnvs[dir], envName)
+	}
+
+	var findings []config.Finding
+	for _, dir := range dirs {
+		slog.Info("Running terraform validate", "working_dir", dir, "environments", dirEnvs[dir])
+		for _, finding := range checkTerraform(ctx, dir) {
+			for _, envName := range dirEnvs[dir] {
+				finding.Environment = envName
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+// checkTerraform runs terraform validate on the working directory.
+func checkTerraform(ctx context.Context, workingDir string) []config.Finding {
+	executor := terraform.NewExecutor(workingDir, "")
+	executor.SetGracePeriod(gracePeriod)
+
+	if err := executor.ValidateWorkingDirectory(); err != nil {
+		return []config.Finding{{Severity: config.SeverityError, Subject: "terraform " + workingDir, Message: err.Error()}}
+	}
+	if err := executor.Validate(ctx); err != nil {
+		return []config.Finding{{Severity: config.SeverityError, Subject: "terraform " + workingDir, Message: err.Error()}}
+	}
+	return nil
+}
+
+// printFindings prints the findings as human-readable text.
+func printFindings(report validateReport) {
+	icons := map[string]string{
+		config.SeverityError:   "❌",
+		config.SeverityWarning: "⚠️ ",
+		config.SeverityInfo:    "ℹ️ ",
+	}
+
+	for _, f := range report.Findings {
+		scope := f.Subject
+		if f.Environment != "" {
+			scope = fmt.Sprintf("%s: %s", f.Environment, f.Subject)
+		}
+		fmt.Printf("%s %-7s %s: %s\n", icons[f.Severity], f.Severity, scope, f.Message)
+	}
+
+	if report.Errors == 0 {
+		fmt.Printf("✅ Validated %d environment(s): %d warning(s)\n", len(report.Environments), report.Warnings)
+	} else {
+		fmt.Printf("Validated %d environment(s): %d error(s), %d warning(s)\n", len(report.Environments), report.Errors, report.Warnings)
+	}
+}
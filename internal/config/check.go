@@ -0,0 +1,136 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/marcy326/tivor/internal/backend"
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+// Severity levels of findings
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Finding is a single result of checking the configuration
+type Finding struct {
+	Severity    string `json:"severity"`
+	Environment string `json:"environment,omitempty"`
+	Subject     string `json:"subject,omitempty"`
+	Message     string `json:"message"`
+}
+
+// CheckSecrets checks the secret management configuration.
+func (c *Config) CheckSecrets() []Finding {
+	if c.Secrets == nil {
+		return nil
+	}
+
+	var findings []Finding
+	switch c.Secrets.Engine {
+	case "sops":
+		if c.Secrets.SopsConfigPath != "" {
+			if _, err := os.Stat(c.Secrets.SopsConfigPath); err != nil {
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Subject:  "secrets",
+					Message:  fmt.Sprintf("sops config file %s not found", c.Secrets.SopsConfigPath),
+				})
+			}
+		}
+		if _, err := exec.LookPath("sops"); err != nil {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Subject:  "secrets",
+				Message:  "sops binary not found in PATH",
+			})
+		}
+		// Until decryption is implemented, SOPS values are not known to be sensitive
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Subject:  "secrets",
+			Message:  "sops decryption is not implemented: encrypted vars files are not decrypted and their variables are not marked sensitive; list them under sensitive to redact them",
+		})
+	case "":
+		findings = append(findings, Finding{
+			Severity: SeverityError,
+			Subject:  "secrets",
+			Message:  "secrets engine is required",
+		})
+	default:
+		findings = append(findings, Finding{
+			Severity: SeverityError,
+			Subject:  "secrets",
+			Message:  fmt.Sprintf("unsupported secrets engine %q (supported: sops)", c.Secrets.Engine),
+		})
+	}
+
+	return findings
+}
+
+// CheckEnvironment resolves an environment, then fetches and parses every vars file,
+// reporting every problem found instead of stopping at the first one.
+func (c *Config) CheckEnvironment(ctx context.Context, envName string) []Finding {
+	finding := func(severity, subject, format string, args ...interface{}) Finding {
+		return Finding{
+			Severity:    severity,
+			Environment: envName,
+			Subject:     subject,
+			Message:     fmt.Sprintf(format, args...),
+		}
+	}
+
+	env, err := c.ResolveEnvironment(envName)
+	if err != nil {
+		return []Finding{finding(SeverityError, "environment", "failed to resolve environment: %v", err)}
+	}
+
+	var findings []Finding
+
+	sources, err := c.newVarsSources(env)
+	if err != nil {
+		return append(findings, finding(SeverityError, "vars_backend", "%v", err))
+	}
+
+	if len(env.VarsFiles) == 0 && len(env.Vars) == 0 {
+		findings = append(findings, finding(SeverityWarning, "vars_files", "no vars files or inline vars configured"))
+	}
+
+	for _, varsFile := range env.VarsFiles {
+		varsSources, err := sources.expand(ctx, []VarsFile{varsFile})
+		if err != nil {
+			findings = append(findings, finding(SeverityError, varsFile.Path, "%v", err))
+			continue
+		}
+
+		for _, source := range varsSources {
+			variables, _, err := readVariables(ctx, source)
+			if err != nil {
+				if source.Optional && errors.Is(err, backend.ErrNotFound) {
+					findings = append(findings, finding(SeverityInfo, source.Label, "optional vars file not found"))
+					continue
+				}
+				findings = append(findings, finding(SeverityError, source.Label, "failed to load vars file: %v", err))
+				continue
+			}
+
+			if len(variables) == 0 {
+				findings = append(findings, finding(SeverityWarning, source.Label, "vars file defines no variables"))
+			}
+		}
+	}
+
+	if len(env.Vars) > 0 {
+		if _, err := tfvars.FromMap(env.Vars, ""); err != nil {
+			findings = append(findings, finding(SeverityError, "vars", "%v", err))
+		}
+	}
+
+	return findings
+}
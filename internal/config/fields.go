@@ -0,0 +1,44 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// yamlField describes a struct field as it appears in tivor.yaml
+type yamlField struct {
+	Name     string
+	Required bool
+	Type     reflect.Type
+}
+
+// yamlFields returns the YAML fields of a struct type in declaration order.
+// Fields tagged schema:"required" are required; version and environments are not,
+// as included files may omit them.
+func yamlFields(t reflect.Type) []yamlField {
+	var fields []yamlField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields = append(fields, yamlField{
+			Name:     name,
+			Required: field.Tag.Get("schema") == "required",
+			Type:     field.Type,
+		})
+	}
+	return fields
+}
@@ -0,0 +1,69 @@
+package config
+
+// Hook events in the order they run
+const (
+	HookPreInit   = "pre_init"
+	HookPrePlan   = "pre_plan"
+	HookPostPlan  = "post_plan"
+	HookPreApply  = "pre_apply"
+	HookPostApply = "post_apply"
+	HookOnFailure = "on_failure"
+)
+
+// Hook failure semantics
+const (
+	HookOnErrorFail     = "fail"
+	HookOnErrorContinue = "continue"
+)
+
+// HookEvents lists every supported hook event
+var HookEvents = []string{
+	HookPreInit,
+	HookPrePlan,
+	HookPostPlan,
+	HookPreApply,
+	HookPostApply,
+	HookOnFailure,
+}
+
+// For returns the hooks registered for the given event
+func (h *Hooks) For(event string) []Hook {
+	if h == nil {
+		return nil
+	}
+
+	switch event {
+	case HookPreInit:
+		return h.PreInit
+	case HookPrePlan:
+		return h.PrePlan
+	case HookPostPlan:
+		return h.PostPlan
+	case HookPreApply:
+		return h.PreApply
+	case HookPostApply:
+		return h.PostApply
+	case HookOnFailure:
+		return h.OnFailure
+	default:
+		return nil
+	}
+}
+
+// set replaces the hooks registered for the given event
+func (h *Hooks) set(event string, hooks []Hook) {
+	switch event {
+	case HookPreInit:
+		h.PreInit = hooks
+	case HookPrePlan:
+		h.PrePlan = hooks
+	case HookPostPlan:
+		h.PostPlan = hooks
+	case HookPreApply:
+		h.PreApply = hooks
+	case HookPostApply:
+		h.PostApply = hooks
+	case HookOnFailure:
+		h.OnFailure = hooks
+	}
+}
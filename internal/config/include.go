@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// loadIncludes merges the files included by the configuration at path into config.
+// Includes are followed recursively; each file may be included only once.
+// Included files without a version are read as the given version of the main file.
+func loadIncludes(config *Config, path, version string, visited map[string]bool) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config file path (%s): %w", path, err)
+	}
+	visited[absPath] = true
+
+	return mergeIncludes(config, config.Include, path, filepath.Dir(path), version, visited)
+}
+
+// mergeIncludes merges the files matched by patterns, declared in the file at path, into root.
+func mergeIncludes(root *Config, patterns []string, path, rootDir, version string, visited map[string]bool) error {
+	baseDir := filepath.Dir(path)
+
+	for _, pattern := range patterns {
+		matches, err := expandInclude(baseDir, pattern)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, match := range matches {
+			absMatch, err := filepath.Abs(match)
+			if err != nil {
+				return fmt.Errorf("%s: failed to resolve included file %s: %w", path, match, err)
+			}
+			if visited[absMatch] {
+				return fmt.Errorf("%s: config file %s is included more than once", path, match)
+			}
+			visited[absMatch] = true
+
+			included, _, err := parseConfigFile(match, version)
+			if err != nil {
+				return err
+			}
+			if err := mergeIncluded(root, included, match, rootDir); err != nil {
+				return err
+			}
+			if err := mergeIncludes(root, included.Include, match, rootDir, version, visited); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// expandInclude returns the files matching an include pattern in lexical order.
+// A literal path that does not exist is an error.
+func expandInclude(baseDir, pattern string) ([]string, error) {
+	fullPattern := pattern
+	if !filepath.IsAbs(pattern) {
+		fullPattern = filepath.Join(baseDir, pattern)
+	}
+
+	matches, err := filepath.Glob(fullPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern %s: %w", pattern, err)
+	}
+	if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		return nil, fmt.Errorf("included file not found: %s", fullPattern)
+	}
+
+	return matches, nil
+}
+
+// mergeIncluded merges the defaults, secrets and environments of an included file into root.
+func mergeIncluded(root, included *Config, source, rootDir string) error {
+	// Both versions have been upgraded to the latest one by now, so compare what was written
+	if included.Version != "" && included.Version != root.Version {
+		return fmt.Errorf("%s: version %s does not match version %s of the main config file", source, included.Version, root.Version)
+	}
+
+	// Keep relative paths pointing at the same files as seen from the included file
+	if err := anchorPaths(included, source, rootDir); err != nil {
+		return err
+	}
+
+	if included.Defaults != nil {
+		if err := validateDefaults(included.Defaults); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		if err := mergeDefaults(root, included.Defaults, source); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+
+	if included.Secrets != nil {
+		if root.Secrets != nil {
+			return fmt.Errorf("%s: secrets are already configured in another file", source)
+		}
+		root.Secrets = included.Secrets
+	}
+
+	for _, env := range included.Environments {
+		env.Source = source
+		root.Environments = append(root.Environments, env)
+	}
+
+	return nil
+}
+
+// anchorPaths rebases the relative paths of config, read from the file at source, onto
+// rootDir, the main file's directory, and records the directory of source on its vars
+// files and backends, so relative local paths resolve against the file that declared them.
+func anchorPaths(config *Config, source, rootDir string) error {
+	rebase, err := filepath.Rel(rootDir, filepath.Dir(source))
+	if err != nil {
+		return fmt.Errorf("%s: failed to rebase relative paths: %w", source, err)
+	}
+	absRootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return fmt.Errorf("%s: failed to rebase relative paths: %w", source, err)
+	}
+	declDir := filepath.Join(absRootDir, rebase)
+
+	if config.Defaults != nil {
+		config.Defaults.VarsFiles = rebaseVarsFiles(rebase, absRootDir, declDir, config.Defaults.VarsFiles)
+	}
+	for i := range config.Environments {
+		env := &config.Environments[i]
+		env.VarsFiles = rebaseVarsFiles(rebase, absRootDir, declDir, env.VarsFiles)
+		env.WorkingDir = rebasePath(rebase, env.WorkingDir)
+		if env.Backend != nil {
+			env.Backend.dir = declDir
+		}
+	}
+	return nil
+}
+
+// mergeDefaults merges the defaults included from source into root, rejecting settings defined twice.
+func mergeDefaults(root *Config, defaults *Defaults, source string) error {
+	if root.Defaults == nil {
+		root.Defaults = &Defaults{}
+	}
+
+	root.Defaults.VarsFiles = deduplicateSlice(append(root.Defaults.VarsFiles, defaults.VarsFiles...))
+
+	for name, value := range defaults.Vars {
+		if _, ok := root.Defaults.Vars[name]; ok {
+			return fmt.Errorf("default variable %s is already defined in another file", name)
+		}
+		if root.Defaults.Vars == nil {
+			root.Defaults.Vars = make(map[string]interface{})
+		}
+		if root.Defaults.varSources == nil {
+			root.Defaults.varSources = make(map[string]string)
+		}
+		root.Defaults.Vars[name] = value
+		root.Defaults.varSources[name] = source
+	}
+
+	if defaults.Execution != nil {
+		if root.Defaults.Execution != nil {
+			return fmt.Errorf("default execution settings are already defined in another file")
+		}
+		root.Defaults.Execution = defaults.Execution
+	}
+
+	if defaults.Hooks != nil {
+		if root.Defaults.Hooks != nil {
+			return fmt.Errorf("default hooks are already defined in another file")
+		}
+		root.Defaults.Hooks = defaults.Hooks
+	}
+
+	return nil
+}
+
+// rebaseVarsFiles prefixes relative vars file paths with dir, the declaring file's
+// directory relative to rootDir, and records rootDir and declDir on them.
+func rebaseVarsFiles(dir, rootDir, declDir string, varsFiles []VarsFile) []VarsFile {
+	if len(varsFiles) == 0 {
+		return varsFiles
+	}
+
+	rebased := make([]VarsFile, len(varsFiles))
+	for i, varsFile := range varsFiles {
+		rebased[i] = varsFile
+		if varsFile.Source != nil {
+			varsFile.Source.dir = declDir
+			continue
+		}
+		// Paths of other sources are not files next to the configuration
+		if !isSourceURI(varsFile.Path) && isRelativePath(varsFile.Path) {
+			rebased[i].Path = rebasePath(dir, varsFile.Path)
+			rebased[i].baseDir = rootDir
+			rebased[i].declDir = declDir
+		}
+	}
+	return rebased
+}
+
+// rebasePath prefixes a relative path with dir.
+// Empty and absolute paths and paths starting with a reference are returned unchanged.
+func rebasePath(dir, path string) string {
+	if dir == "." || !isRelativePath(path) {
+		return path
+	}
+	return filepath.ToSlash(filepath.Join(dir, path))
+}
+
+// isRelativePath reports whether path is a non-empty relative path without a reference.
+func isRelativePath(path string) bool {
+	return path != "" && !filepath.IsAbs(path) && !strings.HasPrefix(path, "$")
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncludedVarsFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "tivor.yaml"), `version: "1.1"
+include:
+  - envs/*.yaml
+environments:
+  - name: main
+    vars_files:
+      - vars/main.tfvars
+  - name: main-data
+    vars_backend:
+      type: local
+      config:
+        path: data
+    vars_files:
+      - prod.tfvars
+`)
+	writeFile(t, filepath.Join(root, "envs", "dev.yaml"), `environments:
+  - name: dev
+    vars_files:
+      - vars/dev.tfvars
+      - vars/*.auto.tfvars
+`)
+	writeFile(t, filepath.Join(root, "envs", "prod.yaml"), `environments:
+  - name: prod
+    vars_backend:
+      type: local
+      config:
+        path: data
+    vars_files:
+      - prod.tfvars
+      - "*.auto.tfvars"
+`)
+	writeFile(t, filepath.Join(root, "vars", "main.tfvars"), "region = \"main\"\n")
+	writeFile(t, filepath.Join(root, "data", "prod.tfvars"), "region = \"main-data\"\n")
+	writeFile(t, filepath.Join(root, "envs", "vars", "dev.tfvars"), "region = \"dev\"\n")
+	writeFile(t, filepath.Join(root, "envs", "vars", "a.auto.tfvars"), "size = 1\n")
+	writeFile(t, filepath.Join(root, "envs", "data", "prod.tfvars"), "region = \"prod\"\n")
+	writeFile(t, filepath.Join(root, "envs", "data", "b.auto.tfvars"), "size = 2\n")
+
+	cfg, err := LoadConfig(filepath.Join(root, "tivor.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	// Run from another directory with decoys at the paths relative to it
+	wd := t.TempDir()
+	writeFile(t, filepath.Join(wd, "vars", "main.tfvars"), "region = \"decoy\"\n")
+	writeFile(t, filepath.Join(wd, "envs", "vars", "dev.tfvars"), "region = \"decoy\"\n")
+	writeFile(t, filepath.Join(wd, "data", "prod.tfvars"), "region = \"decoy\"\n")
+	chdir(t, wd)
+
+	tests := []struct {
+		env        string
+		wantLabels []string
+		want       []string
+	}{
+		{
+			env:        "main",
+			wantLabels: []string{"vars/main.tfvars"},
+			want:       []string{`region = "main"`},
+		},
+		{
+			// The backend path is relative to the main file
+			env:        "main-data",
+			wantLabels: []string{"prod.tfvars"},
+			want:       []string{`region = "main-data"`},
+		},
+		{
+			env:        "dev",
+			wantLabels: []string{"envs/vars/dev.tfvars", "envs/vars/a.auto.tfvars"},
+			want:       []string{`region = "dev"`, "size = 1"},
+		},
+		{
+			// The same backend path is relative to the included file
+			env:        "prod",
+			wantLabels: []string{"envs/prod.tfvars", "envs/b.auto.tfvars"},
+			want:       []string{`region = "prod"`, "size = 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			env, err := cfg.ResolveEnvironment(tt.env)
+			if err != nil {
+				t.Fatalf("ResolveEnvironment() error = %v", err)
+			}
+			sources, err := cfg.newVarsSources(env)
+			if err != nil {
+				t.Fatalf("newVarsSources() error = %v", err)
+			}
+			expanded, err := sources.expand(context.Background(), env.VarsFiles)
+			if err != nil {
+				t.Fatalf("expand() error = %v", err)
+			}
+			var labels []string
+			for _, source := range expanded {
+				labels = append(labels, source.Label)
+			}
+			if strings.Join(labels, ",") != strings.Join(tt.wantLabels, ",") {
+				t.Errorf("labels = %v, want %v", labels, tt.wantLabels)
+			}
+
+			content, err := cfg.LoadVarsFiles(context.Background(), tt.env)
+			if err != nil {
+				t.Fatalf("LoadVarsFiles() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("vars = %q, want it to contain %q", content, want)
+				}
+			}
+		})
+	}
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
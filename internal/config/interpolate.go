@@ -0,0 +1,290 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// referencePattern matches ${env:NAME}, ${env.ATTR} and ${var.NAME} references,
+// and ${matrix.AXIS} references left over outside matrix environments.
+// A leading "$$" escapes the reference. Other ${...} sequences, such as shell
+// variables in hook commands, are left untouched.
+var referencePattern = regexp.MustCompile(`\$?\$\{(env:|env\.|var\.|matrix\.)([^}]*)\}`)
+
+// interpolator resolves references for a single resolved environment,
+// or only ${env:NAME} references outside environments if env is nil
+type interpolator struct {
+	env *Environment
+	// workspace is false until the workspace has been interpolated
+	workspace bool
+	// vars holds the inline variables as written, so each is interpolated exactly once
+	vars      map[string]interface{}
+	resolved  map[string]string
+	resolving map[string]bool
+}
+
+// interpolateEnvironment resolves references in every string field of a resolved environment.
+// Shared values such as backend config and hooks are copied rather than modified.
+func interpolateEnvironment(env *Environment) error {
+	in := &interpolator{
+		env:       env,
+		vars:      env.Vars,
+		resolved:  make(map[string]string),
+		resolving: make(map[string]bool),
+	}
+
+	// The workspace first, so ${env.workspace} in other fields sees its interpolated value
+	var err error
+	if env.Workspace, err = in.string(env.Workspace); err != nil {
+		return fmt.Errorf("workspace: %w", err)
+	}
+	in.workspace = true
+
+	// Inline variables next, so later fields see their interpolated values
+	if env.Vars != nil {
+		names := make([]string, 0, len(env.Vars))
+		for name := range env.Vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		vars := make(map[string]interface{}, len(env.Vars))
+		for _, name := range names {
+			interpolated, err := in.value(env.Vars[name])
+			if err != nil {
+				return fmt.Errorf("vars.%s: %w", name, err)
+			}
+			vars[name] = interpolated
+		}
+		env.Vars = vars
+	}
+
+	if env.VarsFiles != nil {
+		varsFiles := make([]VarsFile, len(env.VarsFiles))
+		for i, varsFile := range env.VarsFiles {
+			varsFiles[i] = varsFile
+			if varsFiles[i].Path, err = in.string(varsFile.Path); err != nil {
+				return fmt.Errorf("vars_files[%d]: %w", i, err)
+			}
+			if varsFile.Source != nil {
+				if varsFiles[i].Source, err = in.backend(varsFile.Source); err != nil {
+					return fmt.Errorf("vars_files[%d].source.%w", i, err)
+				}
+			}
+		}
+		env.VarsFiles = varsFiles
+	}
+	if env.PlanArgs, err = in.strings("plan_args", env.PlanArgs); err != nil {
+		return err
+	}
+	if env.ApplyArgs, err = in.strings("apply_args", env.ApplyArgs); err != nil {
+		return err
+	}
+	if env.WorkingDir, err = in.string(env.WorkingDir); err != nil {
+		return fmt.Errorf("working_dir: %w", err)
+	}
+
+	if env.Backend != nil {
+		if env.Backend, err = in.backend(env.Backend); err != nil {
+			return fmt.Errorf("vars_backend.%w", err)
+		}
+	}
+
+	if env.Hooks != nil {
+		hooks := &Hooks{}
+		for _, event := range HookEvents {
+			list := env.Hooks.For(event)
+			if list == nil {
+				continue
+			}
+			interpolated := make([]Hook, len(list))
+			for i, hook := range list {
+				interpolated[i] = hook
+				if interpolated[i].Command, err = in.string(hook.Command); err != nil {
+					return fmt.Errorf("hooks.%s[%d]: %w", event, i, err)
+				}
+			}
+			hooks.set(event, interpolated)
+		}
+		env.Hooks = hooks
+	}
+
+	return nil
+}
+
+// interpolateSecrets resolves ${env:NAME} references in the secrets configuration.
+// It belongs to no environment, so other references are errors.
+func interpolateSecrets(secrets *Secrets) error {
+	in := &interpolator{}
+
+	var err error
+	if secrets.Engine, err = in.string(secrets.Engine); err != nil {
+		return fmt.Errorf("secrets.engine: %w", err)
+	}
+	if secrets.SopsConfigPath, err = in.string(secrets.SopsConfigPath); err != nil {
+		return fmt.Errorf("secrets.sops_config_path: %w", err)
+	}
+	return nil
+}
+
+// backend interpolates a backend configuration into a new Backend.
+// Errors start with the name of the failing field.
+func (in *interpolator) backend(b *Backend) (*Backend, error) {
+	result := &Backend{dir: b.dir}
+
+	var err error
+	if result.Type, err = in.string(b.Type); err != nil {
+		return nil, fmt.Errorf("type: %w", err)
+	}
+	if b.Config != nil {
+		config, err := in.value(b.Config)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		result.Config = config.(map[string]interface{})
+	}
+
+	return result, nil
+}
+
+// strings interpolates every element of a string slice into a new slice
+func (in *interpolator) strings(field string, values []string) ([]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	result := make([]string, len(values))
+	for i, value := range values {
+		interpolated, err := in.string(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+		}
+		result[i] = interpolated
+	}
+	return result, nil
+}
+
+// value interpolates strings inside a value decoded from YAML, copying maps and slices
+func (in *interpolator) value(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return in.string(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			interpolated, err := in.value(item)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = interpolated
+		}
+		return result, nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			interpolated, err := in.value(item)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = interpolated
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+// string replaces every reference in s
+func (in *interpolator) string(s string) (string, error) {
+	var firstErr error
+
+	result := referencePattern.ReplaceAllStringFunc(s, func(match string) string {
+		// "$${...}" is an escaped reference and becomes a literal "${...}"
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		groups := referencePattern.FindStringSubmatch(match)
+		resolved, err := in.reference(groups[1], groups[2])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", match, err)
+			}
+			return match
+		}
+		return resolved
+	})
+
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return result, nil
+}
+
+// reference resolves a single reference of the given kind
+func (in *interpolator) reference(kind, name string) (string, error) {
+	switch kind {
+	case "env:":
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+
+	case "env.":
+		if in.env == nil {
+			return "", fmt.Errorf("environment attributes are only valid in environment fields")
+		}
+		switch name {
+		case "name":
+			return in.env.Name, nil
+		case "workspace":
+			if !in.workspace {
+				return "", fmt.Errorf("the workspace cannot reference itself")
+			}
+			return in.env.Workspace, nil
+		default:
+			return "", fmt.Errorf("unknown environment attribute %q (supported: name, workspace)", name)
+		}
+
+	case "var.":
+		if in.env == nil {
+			return "", fmt.Errorf("variables are only valid in environment fields")
+		}
+		raw, ok := in.vars[name]
+		if !ok {
+			return "", fmt.Errorf("variable %s is not defined in vars", name)
+		}
+		if resolved, ok := in.resolved[name]; ok {
+			return resolved, nil
+		}
+		if in.resolving[name] {
+			return "", fmt.Errorf("variable %s references itself", name)
+		}
+
+		switch v := raw.(type) {
+		case string:
+			in.resolving[name] = true
+			defer delete(in.resolving, name)
+			resolved, err := in.string(v)
+			if err != nil {
+				return "", err
+			}
+			in.resolved[name] = resolved
+			return resolved, nil
+		case bool, int, int64, uint64, float64:
+			return fmt.Sprint(v), nil
+		default:
+			return "", fmt.Errorf("variable %s is not a scalar value", name)
+		}
+
+	case "matrix.":
+		return "", fmt.Errorf("matrix references are only valid in environments with a matrix")
+
+	default:
+		return "", fmt.Errorf("unknown reference kind %q", kind)
+	}
+}
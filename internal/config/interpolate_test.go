@@ -0,0 +1,163 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterpolateEnvironment(t *testing.T) {
+	t.Setenv("TIVOR_TEST_REGION", "eu-west-1")
+
+	tests := []struct {
+		name          string
+		env           Environment
+		wantWorkspace string
+		wantVars      map[string]interface{}
+		wantVarsFiles []string
+		wantErr       string
+	}{
+		{
+			name: "escaped reference in a var is expanded once",
+			env: Environment{
+				Name:      "dev",
+				Workspace: "ws-${var.a}",
+				Vars:      map[string]interface{}{"a": "$${env:HOME}"},
+			},
+			wantWorkspace: "ws-${env:HOME}",
+			wantVars:      map[string]interface{}{"a": "${env:HOME}"},
+		},
+		{
+			name: "var referencing a var",
+			env: Environment{
+				Name:      "dev",
+				Workspace: "${var.b}",
+				Vars:      map[string]interface{}{"a": "${env:TIVOR_TEST_REGION}", "b": "${var.a}-${env.name}"},
+			},
+			wantWorkspace: "eu-west-1-dev",
+			wantVars:      map[string]interface{}{"a": "eu-west-1", "b": "eu-west-1-dev"},
+		},
+		{
+			name: "non-string var",
+			env: Environment{
+				Name:      "dev",
+				Workspace: "shard-${var.count}",
+				Vars:      map[string]interface{}{"count": 3},
+			},
+			wantWorkspace: "shard-3",
+			wantVars:      map[string]interface{}{"count": 3},
+		},
+		{
+			name: "workspace from the environment in vars and vars files",
+			env: Environment{
+				Name:      "dev",
+				Workspace: "${env:TIVOR_TEST_REGION}",
+				Vars:      map[string]interface{}{"ws": "${env.workspace}"},
+				VarsFiles: []VarsFile{{Path: "vars/${env.workspace}.tfvars"}},
+			},
+			wantWorkspace: "eu-west-1",
+			wantVars:      map[string]interface{}{"ws": "eu-west-1"},
+			wantVarsFiles: []string{"vars/eu-west-1.tfvars"},
+		},
+		{
+			name:    "workspace referencing itself",
+			env:     Environment{Name: "dev", Workspace: "${var.ws}", Vars: map[string]interface{}{"ws": "${env.workspace}"}},
+			wantErr: "cannot reference itself",
+		},
+		{
+			name:    "self reference",
+			env:     Environment{Name: "dev", Vars: map[string]interface{}{"a": "${var.a}"}},
+			wantErr: "references itself",
+		},
+		{
+			name:    "undefined var",
+			env:     Environment{Name: "dev", Workspace: "${var.missing}"},
+			wantErr: "not defined in vars",
+		},
+		{
+			name:    "unset environment variable",
+			env:     Environment{Name: "dev", Workspace: "${env:TIVOR_TEST_UNSET}"},
+			wantErr: "TIVOR_TEST_UNSET is not set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.env
+			err := interpolateEnvironment(&env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("interpolateEnvironment() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("interpolateEnvironment() error = %v", err)
+			}
+			if env.Workspace != tt.wantWorkspace {
+				t.Errorf("workspace = %q, want %q", env.Workspace, tt.wantWorkspace)
+			}
+			if !reflect.DeepEqual(env.Vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", env.Vars, tt.wantVars)
+			}
+			var varsFiles []string
+			for _, varsFile := range env.VarsFiles {
+				varsFiles = append(varsFiles, varsFile.Path)
+			}
+			if !reflect.DeepEqual(varsFiles, tt.wantVarsFiles) {
+				t.Errorf("vars_files = %v, want %v", varsFiles, tt.wantVarsFiles)
+			}
+		})
+	}
+}
+
+func TestInterpolateSecrets(t *testing.T) {
+	t.Setenv("TIVOR_TEST_SOPS_DIR", "/etc/sops")
+
+	tests := []struct {
+		name    string
+		secrets Secrets
+		want    Secrets
+		wantErr string
+	}{
+		{
+			name:    "environment variable",
+			secrets: Secrets{Engine: "sops", SopsConfigPath: "${env:TIVOR_TEST_SOPS_DIR}/.sops.yaml"},
+			want:    Secrets{Engine: "sops", SopsConfigPath: "/etc/sops/.sops.yaml"},
+		},
+		{
+			name:    "escaped reference",
+			secrets: Secrets{Engine: "sops", SopsConfigPath: "$${env:X}"},
+			want:    Secrets{Engine: "sops", SopsConfigPath: "${env:X}"},
+		},
+		{
+			name:    "environment attribute",
+			secrets: Secrets{Engine: "sops", SopsConfigPath: "${env.name}.sops.yaml"},
+			wantErr: "secrets.sops_config_path: ${env.name}: environment attributes are only valid in environment fields",
+		},
+		{
+			name:    "variable",
+			secrets: Secrets{Engine: "${var.engine}"},
+			wantErr: "secrets.engine: ${var.engine}: variables are only valid in environment fields",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets := tt.secrets
+			err := interpolateSecrets(&secrets)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("interpolateSecrets() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("interpolateSecrets() error = %v", err)
+			}
+			if secrets != tt.want {
+				t.Errorf("secrets = %+v, want %+v", secrets, tt.want)
+			}
+		})
+	}
+}
@@ -2,40 +2,129 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/marcy326/tivor/internal/backend"
-	"github.com/marcy326/tivor/internal/backend/local"
+	"github.com/marcy326/tivor/internal/redact"
+	"github.com/marcy326/tivor/internal/terraform"
 	"github.com/marcy326/tivor/internal/tfvars"
 	"gopkg.in/yaml.v3"
 )
 
-// LoadConfig loads and parses tivor.yaml from the specified path into Config.
+// LoadConfig loads and parses tivor.yaml from the specified path into Config,
+// following include directives.
 func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+	config, version, err := parseConfigFile(path, "")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file (%s): %w", path, err)
+		return nil, err
+	}
+	config.path = path
+	if err := anchorPaths(config, path, filepath.Dir(path)); err != nil {
+		return nil, err
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file (%s): %w", path, err)
+	// Merge included files
+	if err := loadIncludes(config, path, version, map[string]bool{}); err != nil {
+		return nil, err
 	}
 
 	// Validation
-	if err := validateConfig(&config); err != nil {
+	if err := validateConfig(config); err != nil {
 		return nil, fmt.Errorf("invalid config file (%s): %w", path, err)
 	}
 
-	return &config, nil
+	// Environment fields are interpolated when resolved; secrets belong to no environment
+	if config.Secrets != nil {
+		if err := interpolateSecrets(config.Secrets); err != nil {
+			return nil, fmt.Errorf("invalid config file (%s): %w", path, err)
+		}
+	}
+
+	return config, nil
+}
+
+// parseConfigFile reads and parses a single configuration file, upgrading older
+// versions in memory. Files without a version are read as defaultVersion.
+// It returns the version the file was written in.
+func parseConfigFile(path, defaultVersion string) (*Config, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read config file (%s): %w", path, err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file (%s): %w", path, err)
+	}
+
+	version := documentVersion(&root)
+	if version == "" {
+		version = defaultVersion
+	}
+
+	// Upgrade older versions before checking fields against the current types
+	if version != "" && version != LatestVersion {
+		if !isSupportedVersion(version) {
+			return nil, "", fmt.Errorf("invalid config file (%s): unsupported config version %q (supported versions: %s)", path, version, strings.Join(SupportedVersions, ", "))
+		}
+		deprecations, _, err := migrateNode(&root, version)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to upgrade config file (%s): %w", path, err)
+		}
+		for _, d := range deprecations {
+			slog.Warn("Deprecated config field (run 'tivor migrate' to upgrade)",
+				"file", path, "line", d.Line, "column", d.Column, "message", d.Message)
+		}
+	}
+
+	// Reject unknown keys such as a misspelled var_files, which would otherwise be ignored
+	if err := checkUnknownFields(&root, reflect.TypeOf(Config{}), ""); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file (%s): %w", path, err)
+	}
+
+	// Generate the environments of matrix templates
+	if err := expandMatrices(&root); err != nil {
+		return nil, "", fmt.Errorf("invalid config file (%s): %w", path, err)
+	}
+
+	var config Config
+	if err := root.Decode(&config); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file (%s): %w", path, err)
+	}
+
+	return &config, version, nil
+}
+
+// SupportedVersions lists the config file versions this release can read
+var SupportedVersions = []string{"1.0", LatestVersion}
+
+// isSupportedVersion reports whether version is one of SupportedVersions.
+func isSupportedVersion(version string) bool {
+	for _, supported := range SupportedVersions {
+		if version == supported {
+			return true
+		}
+	}
+	return false
 }
 
 // validateConfig performs basic validation of the configuration file.
+// Errors about environments from included files name the file that declared them.
 func validateConfig(config *Config) error {
 	if config.Version == "" {
 		return fmt.Errorf("version field is required")
 	}
+	if !isSupportedVersion(config.Version) {
+		return fmt.Errorf("unsupported config version %q (supported versions: %s)", config.Version, strings.Join(SupportedVersions, ", "))
+	}
 
 	if len(config.Environments) == 0 {
 		return fmt.Errorf("at least one environment is required")
@@ -43,21 +132,196 @@ func validateConfig(config *Config) error {
 
 	// Check for duplicate environment names
 	envNames := make(map[string]bool)
+	envSources := make(map[string]string)
 	for _, env := range config.Environments {
 		if env.Name == "" {
-			return fmt.Errorf("environment name is required")
+			return withSource(env.Source, fmt.Errorf("environment name is required"))
 		}
 		if envNames[env.Name] {
-			return fmt.Errorf("duplicate environment name: %s", env.Name)
+			declared := envSources[env.Name]
+			if declared == "" {
+				declared = "the main config file"
+			}
+			return withSource(env.Source, fmt.Errorf("duplicate environment name: %s (already declared in %s)", env.Name, declared))
 		}
 		envNames[env.Name] = true
+		envSources[env.Name] = env.Source
+
+		if err := validateEnvironment(&env); err != nil {
+			return withSource(env.Source, err)
+		}
+	}
+
+	if err := validateDefaults(config.Defaults); err != nil {
+		return err
 	}
 
 	// Check inheritance relationships
+	parents := make(map[string]string)
 	for _, env := range config.Environments {
 		if env.Inherits != "" {
 			if !envNames[env.Inherits] {
-				return fmt.Errorf("environment %s inherits from non-existent environment %s", env.Name, env.Inherits)
+				return withSource(env.Source, fmt.Errorf("environment %s inherits from non-existent environment %s", env.Name, env.Inherits))
+			}
+			parents[env.Name] = env.Inherits
+		}
+	}
+
+	// Check for inheritance cycles, which would make resolution loop forever
+	for _, env := range config.Environments {
+		chain := []string{env.Name}
+		seen := map[string]bool{env.Name: true}
+		for parent := parents[env.Name]; parent != ""; parent = parents[parent] {
+			chain = append(chain, parent)
+			if seen[parent] {
+				return withSource(env.Source, fmt.Errorf("inheritance cycle detected: %s", strings.Join(chain, " -> ")))
+			}
+			seen[parent] = true
+		}
+	}
+
+	return nil
+}
+
+// validateEnvironment validates the settings of a single environment.
+func validateEnvironment(env *Environment) error {
+	if err := validateVarsFiles(env.VarsFiles); err != nil {
+		return fmt.Errorf("environment %s has invalid vars_files: %w", env.Name, err)
+	}
+	if err := validateTags(env.Tags); err != nil {
+		return fmt.Errorf("environment %s has invalid tags: %w", env.Name, err)
+	}
+	if err := terraform.ValidateArgs("plan", env.PlanArgs); err != nil {
+		return fmt.Errorf("environment %s has invalid plan_args: %w", env.Name, err)
+	}
+	if err := terraform.ValidateArgs("apply", env.ApplyArgs); err != nil {
+		return fmt.Errorf("environment %s has invalid apply_args: %w", env.Name, err)
+	}
+	if err := validateExecution(env.Execution); err != nil {
+		return fmt.Errorf("environment %s has invalid execution settings: %w", env.Name, err)
+	}
+	if err := validateHooks(env.Hooks); err != nil {
+		return fmt.Errorf("environment %s has invalid hooks: %w", env.Name, err)
+	}
+	if err := validateVars(env.Vars); err != nil {
+		return fmt.Errorf("environment %s has invalid vars: %w", env.Name, err)
+	}
+	if err := validateSensitive(env.Sensitive); err != nil {
+		return fmt.Errorf("environment %s has invalid sensitive variables: %w", env.Name, err)
+	}
+	return nil
+}
+
+// validateDefaults validates the default settings.
+func validateDefaults(defaults *Defaults) error {
+	if defaults == nil {
+		return nil
+	}
+
+	if err := validateVarsFiles(defaults.VarsFiles); err != nil {
+		return fmt.Errorf("defaults have invalid vars_files: %w", err)
+	}
+	if err := validateExecution(defaults.Execution); err != nil {
+		return fmt.Errorf("defaults have invalid execution settings: %w", err)
+	}
+	if err := validateHooks(defaults.Hooks); err != nil {
+		return fmt.Errorf("defaults have invalid hooks: %w", err)
+	}
+	if err := validateVars(defaults.Vars); err != nil {
+		return fmt.Errorf("defaults have invalid vars: %w", err)
+	}
+	if err := validateSensitive(defaults.Sensitive); err != nil {
+		return fmt.Errorf("defaults have invalid sensitive variables: %w", err)
+	}
+	return nil
+}
+
+// withSource prefixes err with the file it originates from, if known.
+func withSource(source string, err error) error {
+	if source == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", source, err)
+}
+
+// sha256Pattern matches a hex-encoded SHA-256 checksum
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// validateVarsFiles validates vars_files entries.
+func validateVarsFiles(varsFiles []VarsFile) error {
+	for _, varsFile := range varsFiles {
+		if varsFile.Source != nil {
+			if varsFile.Source.Type == "" {
+				return fmt.Errorf("%s: source requires a type", varsFile.Path)
+			}
+			if isSourceURI(varsFile.Path) {
+				return fmt.Errorf("%s: a path with a URI scheme cannot also have a source", varsFile.Path)
+			}
+		} else if _, _, err := parseSourceURI(varsFile.Path); err != nil {
+			return err
+		}
+
+		if varsFile.SHA256 == "" {
+			continue
+		}
+		if varsFile.IsPattern() {
+			return fmt.Errorf("%s: sha256 cannot be used with a glob pattern", varsFile.Path)
+		}
+		if !sha256Pattern.MatchString(varsFile.SHA256) {
+			return fmt.Errorf("%s: sha256 must be 64 hexadecimal characters", varsFile.Path)
+		}
+	}
+	return nil
+}
+
+// validateExecution validates timeouts and retry settings.
+func validateExecution(execution *Execution) error {
+	if execution == nil {
+		return nil
+	}
+
+	if t := execution.Timeouts; t != nil {
+		if t.Init < 0 || t.Plan < 0 || t.Apply < 0 {
+			return fmt.Errorf("timeouts must not be negative")
+		}
+	}
+
+	if r := execution.Retry; r != nil {
+		if r.MaxAttempts < 0 {
+			return fmt.Errorf("retry max_attempts must not be negative")
+		}
+		if r.InitialBackoff < 0 || r.MaxBackoff < 0 {
+			return fmt.Errorf("retry backoff must not be negative")
+		}
+		for _, pattern := range r.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid retry pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	if err := terraform.ValidateVarsInjection(execution.VarsInjection); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateHooks validates the commands and failure semantics of every hook.
+func validateHooks(hooks *Hooks) error {
+	if hooks == nil {
+		return nil
+	}
+
+	for _, event := range HookEvents {
+		for _, hook := range hooks.For(event) {
+			if hook.Command == "" {
+				return fmt.Errorf("%s hook command is required", event)
+			}
+			switch hook.OnError {
+			case "", HookOnErrorFail, HookOnErrorContinue:
+			default:
+				return fmt.Errorf("%s hook has invalid on_error %q (expected %s or %s)", event, hook.OnError, HookOnErrorFail, HookOnErrorContinue)
 			}
 		}
 	}
@@ -65,6 +329,29 @@ func validateConfig(config *Config) error {
 	return nil
 }
 
+// validateVars checks that inline variables have valid names and convertible values.
+func validateVars(vars map[string]interface{}) error {
+	for name := range vars {
+		if !tfvars.IsVariableName(name) {
+			return fmt.Errorf("invalid variable name %q", name)
+		}
+	}
+	if _, err := tfvars.FromMap(vars, ""); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateSensitive checks that sensitive entries are valid variable names.
+func validateSensitive(names []string) error {
+	for _, name := range names {
+		if !tfvars.IsVariableName(name) {
+			return fmt.Errorf("invalid variable name %q", name)
+		}
+	}
+	return nil
+}
+
 // GetEnvironment retrieves environment configuration by name.
 func (c *Config) GetEnvironment(name string) (*Environment, error) {
 	for i := range c.Environments {
@@ -75,8 +362,24 @@ func (c *Config) GetEnvironment(name string) (*Environment, error) {
 	return nil, fmt.Errorf("environment %s not found", name)
 }
 
-// ResolveEnvironment returns environment configuration with inheritance resolved.
+// ResolveEnvironment returns environment configuration with inheritance resolved
+// and ${...} references interpolated.
 func (c *Config) ResolveEnvironment(name string) (*Environment, error) {
+	resolved, err := c.resolveInheritance(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Interpolate only after inheritance so inherited templates see the child's name
+	if err := interpolateEnvironment(resolved); err != nil {
+		return nil, fmt.Errorf("environment %s: %w", name, err)
+	}
+
+	return resolved, nil
+}
+
+// resolveInheritance returns environment configuration with inheritance resolved.
+func (c *Config) resolveInheritance(name string) (*Environment, error) {
 	env, err := c.GetEnvironment(name)
 	if err != nil {
 		return nil, err
@@ -86,7 +389,7 @@ func (c *Config) ResolveEnvironment(name string) (*Environment, error) {
 
 	// Merge parent settings if inheritance is defined
 	if env.Inherits != "" {
-		parentEnv, err := c.ResolveEnvironment(env.Inherits)
+		parentEnv, err := c.resolveInheritance(env.Inherits)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve parent environment (%s): %w", env.Inherits, err)
 		}
@@ -95,6 +398,20 @@ func (c *Config) ResolveEnvironment(name string) (*Environment, error) {
 		if resolved.Backend == nil {
 			resolved.Backend = parentEnv.Backend
 		}
+		if resolved.WorkingDir == "" {
+			resolved.WorkingDir = parentEnv.WorkingDir
+		}
+		if len(resolved.PlanArgs) == 0 {
+			resolved.PlanArgs = parentEnv.PlanArgs
+		}
+		if len(resolved.ApplyArgs) == 0 {
+			resolved.ApplyArgs = parentEnv.ApplyArgs
+		}
+		resolved.Tags = mergeTags(parentEnv.Tags, resolved.Tags)
+		resolved.Vars = mergeVars(parentEnv.Vars, resolved.Vars)
+		resolved.Sensitive = mergeSensitive(parentEnv.Sensitive, resolved.Sensitive)
+		resolved.Execution = mergeExecution(parentEnv.Execution, resolved.Execution)
+		resolved.Hooks = mergeHooks(parentEnv.Hooks, resolved.Hooks)
 
 		// Merge VarsFiles from parent and child with deduplication
 		if len(parentEnv.VarsFiles) > 0 {
@@ -102,7 +419,7 @@ func (c *Config) ResolveEnvironment(name string) (*Environment, error) {
 				resolved.VarsFiles = parentEnv.VarsFiles
 			} else {
 				// Add parent settings first, then child settings
-				mergedVarsFiles := make([]string, 0, len(parentEnv.VarsFiles)+len(resolved.VarsFiles))
+				mergedVarsFiles := make([]VarsFile, 0, len(parentEnv.VarsFiles)+len(resolved.VarsFiles))
 				mergedVarsFiles = append(mergedVarsFiles, parentEnv.VarsFiles...)
 				mergedVarsFiles = append(mergedVarsFiles, resolved.VarsFiles...)
 				resolved.VarsFiles = deduplicateSlice(mergedVarsFiles)
@@ -116,80 +433,287 @@ func (c *Config) ResolveEnvironment(name string) (*Environment, error) {
 			resolved.VarsFiles = c.Defaults.VarsFiles
 		} else {
 			// Add default settings first, then environment-specific settings
-			mergedVarsFiles := make([]string, 0, len(c.Defaults.VarsFiles)+len(resolved.VarsFiles))
+			mergedVarsFiles := make([]VarsFile, 0, len(c.Defaults.VarsFiles)+len(resolved.VarsFiles))
 			mergedVarsFiles = append(mergedVarsFiles, c.Defaults.VarsFiles...)
 			mergedVarsFiles = append(mergedVarsFiles, resolved.VarsFiles...)
 			resolved.VarsFiles = deduplicateSlice(mergedVarsFiles)
 		}
 	}
 
+	// Fall back to default inline variables, execution settings and hooks
+	if c.Defaults != nil {
+		resolved.Vars = mergeVars(c.Defaults.Vars, resolved.Vars)
+		resolved.Sensitive = mergeSensitive(c.Defaults.Sensitive, resolved.Sensitive)
+		resolved.Execution = mergeExecution(c.Defaults.Execution, resolved.Execution)
+		resolved.Hooks = mergeHooks(c.Defaults.Hooks, resolved.Hooks)
+	}
+
 	return &resolved, nil
 }
 
+// VarsInjectionMode returns how variables are passed to Terraform, a vars file by default.
+func (e *Execution) VarsInjectionMode() string {
+	if e == nil || e.VarsInjection == "" {
+		return terraform.VarsInjectionFile
+	}
+	return e.VarsInjection
+}
+
+// mergeSensitive adds child sensitive names to base without modifying either slice.
+func mergeSensitive(base, child []string) []string {
+	if len(base) == 0 {
+		return child
+	}
+	merged := make([]string, 0, len(base)+len(child))
+	merged = append(merged, base...)
+	merged = append(merged, child...)
+	return deduplicateSlice(merged)
+}
+
+// mergeVars overlays child inline variables onto base without modifying either map.
+func mergeVars(base, child map[string]interface{}) map[string]interface{} {
+	if len(base) == 0 {
+		return child
+	}
+	if len(child) == 0 {
+		return base
+	}
+
+	merged := make(map[string]interface{}, len(base)+len(child))
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range child {
+		merged[name] = value
+	}
+	return merged
+}
+
+// mergeExecution overlays child execution settings onto base.
+// Timeouts are merged per command; a child retry policy replaces the base one.
+func mergeExecution(base, child *Execution) *Execution {
+	if base == nil {
+		return child
+	}
+	if child == nil {
+		return base
+	}
+
+	merged := &Execution{
+		Timeouts:      base.Timeouts,
+		Retry:         base.Retry,
+		VarsInjection: base.VarsInjection,
+	}
+
+	if child.Timeouts != nil {
+		timeouts := Timeouts{}
+		if base.Timeouts != nil {
+			timeouts = *base.Timeouts
+		}
+		if child.Timeouts.Init != 0 {
+			timeouts.Init = child.Timeouts.Init
+		}
+		if child.Timeouts.Plan != 0 {
+			timeouts.Plan = child.Timeouts.Plan
+		}
+		if child.Timeouts.Apply != 0 {
+			timeouts.Apply = child.Timeouts.Apply
+		}
+		merged.Timeouts = &timeouts
+	}
+
+	if child.Retry != nil {
+		merged.Retry = child.Retry
+	}
+
+	if child.VarsInjection != "" {
+		merged.VarsInjection = child.VarsInjection
+	}
+
+	return merged
+}
+
+// inlineVarsSource is the provenance of the variable name set in the vars block of origin,
+// an environment name or DefaultsOrigin, naming the file that declared it
+func (c *Config) inlineVarsSource(origin, name string) string {
+	file := c.path
+	if origin == DefaultsOrigin {
+		if source := c.Defaults.varSources[name]; source != "" {
+			file = source
+		}
+	} else if env, err := c.GetEnvironment(origin); err == nil && env.Source != "" {
+		file = env.Source
+	}
+	if file == "" {
+		file = "tivor.yaml"
+	}
+	return fmt.Sprintf("%s (vars of %s)", file, origin)
+}
+
 // LoadVarsFiles loads and combines variable files for the specified environment
 func (c *Config) LoadVarsFiles(ctx context.Context, envName string) ([]byte, error) {
+	mergedVariables, err := c.ResolveVariables(ctx, envName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate final tfvars content
+	finalContent := tfvars.GenerateTfvars(mergedVariables, envName)
+
+	return []byte(finalContent), nil
+}
+
+// ResolveVariables loads the vars files and inline vars of the specified environment
+// and merges them, later definitions overriding earlier ones. workingDir is the Terraform
+// working directory scanned for sensitive declarations; if empty, the environment's is used.
+func (c *Config) ResolveVariables(ctx context.Context, envName, workingDir string) ([]tfvars.Variable, error) {
+	return c.loadVariables(ctx, envName, workingDir, nil)
+}
+
+// loadVariables implements ResolveVariables. If lock is not nil, every vars file
+// read is recorded in it.
+func (c *Config) loadVariables(ctx context.Context, envName, workingDir string, lock *EnvironmentLock) ([]tfvars.Variable, error) {
 	// Resolve environment configuration
 	env, err := c.ResolveEnvironment(envName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve environment: %w", err)
 	}
 
-	// Create backend
-	var backendInstance backend.Backend
-	if env.Backend != nil {
-		switch env.Backend.Type {
-		case "local":
-			backendInstance, err = local.New(env.Backend.Config)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create local backend: %w", err)
-			}
-		case "s3":
-			return nil, fmt.Errorf("s3 backend not yet implemented")
-		default:
-			return nil, fmt.Errorf("unknown backend type: %s", env.Backend.Type)
-		}
-	} else {
-		// Default to local backend if no backend is specified
-		backendInstance, err = local.New(backend.Config{})
-		if err != nil {
-			return nil, fmt.Errorf("failed to create default local backend: %w", err)
-		}
+	// Create the backends of the vars files
+	sources, err := c.newVarsSources(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand glob patterns into concrete paths
+	varsFiles, err := sources.expand(ctx, env.VarsFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch and parse all variable files concurrently, then merge them in order
+	results := make([][]tfvars.Variable, len(varsFiles))
+	fetched := make([]fetchedFile, len(varsFiles))
+	errs := make([]error, len(varsFiles))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, maxConcurrentFetches)
+	for i, varsFile := range varsFiles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			results[i], fetched[i], errs[i] = readVariables(ctx, varsFile)
+		}()
 	}
+	wg.Wait()
 
-	// Load and parse all variable files
 	var allVariableSets [][]tfvars.Variable
+	for i, varsFile := range varsFiles {
+		if err := errs[i]; err != nil {
+			if varsFile.Optional && errors.Is(err, backend.ErrNotFound) {
+				slog.Debug("Skipping missing optional vars file", "path", varsFile.Label)
+				if lock != nil {
+					lock.VarsFiles = append(lock.VarsFiles, LockedVarsFile{Path: varsFile.Label, Backend: varsFile.Type, Missing: true})
+				}
+				continue
+			}
+			return nil, fmt.Errorf("failed to load vars file %s: %w", varsFile.Label, err)
+		}
 
-	for _, varsFile := range env.VarsFiles {
-		content, err := backendInstance.GetVarsFile(ctx, varsFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load vars file %s: %w", varsFile, err)
+		if lock != nil {
+			lock.VarsFiles = append(lock.VarsFiles, LockedVarsFile{
+				Path:    varsFile.Label,
+				Backend: varsFile.Type,
+				Version: fetched[i].Version,
+				SHA256:  fetched[i].SHA256,
+			})
 		}
+		allVariableSets = append(allVariableSets, results[i])
+	}
 
-		variables, err := tfvars.ParseTfvars(content)
+	// Inline variables override every vars file
+	if len(env.Vars) > 0 {
+		inlineVariables, err := tfvars.FromMap(env.Vars, "")
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse vars file %s: %w", varsFile, err)
+			return nil, fmt.Errorf("failed to convert inline vars: %w", err)
 		}
-
-		allVariableSets = append(allVariableSets, variables)
+		origins, err := c.VarOrigins(envName)
+		if err != nil {
+			return nil, err
+		}
+		for i := range inlineVariables {
+			if origin, ok := origins[inlineVariables[i].Name]; ok {
+				inlineVariables[i].Source = c.inlineVarsSource(origin, inlineVariables[i].Name)
+			}
+		}
+		allVariableSets = append(allVariableSets, inlineVariables)
 	}
 
 	// Merge all variables (later definitions override earlier ones)
 	mergedVariables := tfvars.MergeVariables(allVariableSets...)
 
-	// Generate final tfvars content
-	finalContent := tfvars.GenerateTfvars(mergedVariables, envName)
+	// Mark declared sensitive variables and redact their values from logs and output
+	sensitive, err := SensitiveNames(env, workingDir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range mergedVariables {
+		if sensitive[mergedVariables[i].Name] {
+			mergedVariables[i].Sensitive = true
+		}
+		if mergedVariables[i].Sensitive {
+			redact.Add(secretValues(mergedVariables[i])...)
+		}
+	}
+	for _, variable := range mergedVariables {
+		slog.Debug("Variable resolved", "environment", envName, "name", variable.Name, "source", variable.Source, "sensitive", variable.Sensitive)
+	}
 
-	return []byte(finalContent), nil
+	return mergedVariables, nil
+}
+
+// mergeHooks overlays child hooks onto base.
+// Hooks are replaced per event; an explicitly empty list disables the inherited hooks.
+func mergeHooks(base, child *Hooks) *Hooks {
+	if base == nil {
+		return child
+	}
+	if child == nil {
+		return base
+	}
+
+	merged := *base
+	if child.PreInit != nil {
+		merged.PreInit = child.PreInit
+	}
+	if child.PrePlan != nil {
+		merged.PrePlan = child.PrePlan
+	}
+	if child.PostPlan != nil {
+		merged.PostPlan = child.PostPlan
+	}
+	if child.PreApply != nil {
+		merged.PreApply = child.PreApply
+	}
+	if child.PostApply != nil {
+		merged.PostApply = child.PostApply
+	}
+	if child.OnFailure != nil {
+		merged.OnFailure = child.OnFailure
+	}
+
+	return &merged
 }
 
-// deduplicateSlice removes duplicate strings from a slice while preserving order
-func deduplicateSlice(slice []string) []string {
+// deduplicateSlice removes duplicate items from a slice while preserving order
+func deduplicateSlice[T comparable](slice []T) []T {
 	if len(slice) == 0 {
 		return slice
 	}
 
-	seen := make(map[string]bool)
-	result := make([]string, 0, len(slice))
+	seen := make(map[T]bool)
+	result := make([]T, 0, len(slice))
 
 	for _, item := range slice {
 		if !seen[item] {
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marcy326/tivor/internal/redact"
+	"github.com/marcy326/tivor/internal/tfvars"
+	"gopkg.in/yaml.v3"
+)
+
+// LockFileName is the name of the lock file, written next to the configuration file
+const LockFileName = "tivor.lock"
+
+// lockFileVersion is the version of the lock file format
+const lockFileVersion = 1
+
+// lockFileHeader is written at the top of the lock file
+const lockFileHeader = `# This file is maintained by "tivor lock".
+# It records the exact vars files of each environment; commit it with tivor.yaml.
+`
+
+// Lock records the inputs each environment resolved to, like .terraform.lock.hcl does for providers
+type Lock struct {
+	Version      int                         `yaml:"version"`
+	Environments map[string]*EnvironmentLock `yaml:"environments"`
+}
+
+// EnvironmentLock records the vars files of an environment and its rendered tfvars
+type EnvironmentLock struct {
+	VarsFiles []LockedVarsFile `yaml:"vars_files"`
+	// OutputSHA256 is the checksum of the rendered tfvars, with sensitive values masked
+	OutputSHA256 string `yaml:"output_sha256"`
+}
+
+// LockedVarsFile records a vars file as it was read
+type LockedVarsFile struct {
+	// Path is the path as written in tivor.yaml, or the match of a glob pattern
+	Path    string `yaml:"path"`
+	Backend string `yaml:"backend"`
+	// Version is the version or ETag reported by remote backends
+	Version string `yaml:"version,omitempty"`
+	// SHA256 is the checksum of the file. Variables read directly from secret stores
+	// are not recorded, so rotating a secret does not invalidate the lock.
+	SHA256 string `yaml:"sha256,omitempty"`
+	// Missing records an optional file that did not exist
+	Missing bool `yaml:"missing,omitempty"`
+}
+
+// NewLock creates an empty lock
+func NewLock() *Lock {
+	return &Lock{Version: lockFileVersion, Environments: make(map[string]*EnvironmentLock)}
+}
+
+// ReadLock reads a lock file. A missing file returns an error wrapping os.ErrNotExist.
+func ReadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	lock := NewLock()
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", path, err)
+	}
+	if lock.Version != lockFileVersion {
+		return nil, fmt.Errorf("unsupported lock file version %d in %s (expected %d)", lock.Version, path, lockFileVersion)
+	}
+	if lock.Environments == nil {
+		lock.Environments = make(map[string]*EnvironmentLock)
+	}
+	return lock, nil
+}
+
+// Write writes the lock file
+func (l *Lock) Write(path string) error {
+	var buf bytes.Buffer
+	buf.WriteString(lockFileHeader)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(l); err != nil {
+		return fmt.Errorf("failed to encode lock file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode lock file: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// LockVarsFiles resolves the variables of an environment like ResolveVariables and also
+// returns the lock entry describing every vars file read
+func (c *Config) LockVarsFiles(ctx context.Context, envName, workingDir string) (*EnvironmentLock, []tfvars.Variable, error) {
+	lock := &EnvironmentLock{}
+	variables, err := c.loadVariables(ctx, envName, workingDir, lock)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	masked := make([]tfvars.Variable, len(variables))
+	for i, variable := range variables {
+		if variable.Sensitive {
+			variable.Value = strconv.Quote(redact.Placeholder)
+		}
+		masked[i] = variable
+	}
+	sum := sha256.Sum256([]byte(tfvars.GenerateTfvars(masked, envName)))
+	lock.OutputSHA256 = hex.EncodeToString(sum[:])
+
+	return lock, variables, nil
+}
+
+// Verify checks that the inputs of an environment match its lock.
+// Versions are informational: only a changed checksum, file list or output fails.
+// Files are matched by backend type and path; repeated files are matched in order.
+func (l *EnvironmentLock) Verify(actual *EnvironmentLock) error {
+	locked := make(map[string][]LockedVarsFile, len(l.VarsFiles))
+	for _, varsFile := range l.VarsFiles {
+		locked[varsFile.key()] = append(locked[varsFile.key()], varsFile)
+	}
+
+	var changes []string
+	for _, varsFile := range actual.VarsFiles {
+		candidates := locked[varsFile.key()]
+		if len(candidates) == 0 {
+			changes = append(changes, fmt.Sprintf("%s is not in the lock", varsFile))
+			continue
+		}
+		expected := candidates[0]
+		locked[varsFile.key()] = candidates[1:]
+
+		switch {
+		case expected.Missing != varsFile.Missing && varsFile.Missing:
+			changes = append(changes, fmt.Sprintf("%s no longer exists", varsFile))
+		case expected.Missing != varsFile.Missing:
+			changes = append(changes, fmt.Sprintf("%s was missing when locked", varsFile))
+		case expected.SHA256 != varsFile.SHA256:
+			changes = append(changes, fmt.Sprintf("%s changed: sha256 %s, locked %s", varsFile, orNone(varsFile.SHA256), orNone(expected.SHA256)))
+		}
+	}
+	var removed []string
+	for _, remaining := range locked {
+		for _, varsFile := range remaining {
+			removed = append(removed, fmt.Sprintf("%s is locked but no longer read", varsFile))
+		}
+	}
+	sort.Strings(removed)
+	changes = append(changes, removed...)
+
+	if len(changes) == 0 && l.OutputSHA256 != actual.OutputSHA256 {
+		changes = append(changes, "rendered tfvars changed (inline vars or vars file order)")
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("inputs do not match %s: %s; run tivor lock to update it", LockFileName, strings.Join(changes, "; "))
+	}
+	return nil
+}
+
+// key identifies a vars file in a lock: the same path can be read from several backends
+func (f LockedVarsFile) key() string {
+	return f.Backend + "\x00" + f.Path
+}
+
+// String describes a vars file in messages
+func (f LockedVarsFile) String() string {
+	return fmt.Sprintf("%s (%s)", f.Path, f.Backend)
+}
+
+// orNone returns s, or "none" if it is empty
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
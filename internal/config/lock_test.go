@@ -0,0 +1,179 @@
+package config
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadTestConfig writes a configuration file to a temporary directory and loads it
+func loadTestConfig(t *testing.T, content string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tivor.yaml")
+	writeFile(t, path, content)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	return cfg
+}
+
+func TestVerify(t *testing.T) {
+	locked := &EnvironmentLock{
+		VarsFiles: []LockedVarsFile{
+			{Path: "common.tfvars", Backend: "local", SHA256: "aaa"},
+			{Path: "common.tfvars", Backend: "git", Version: "blob1", SHA256: "bbb"},
+			{Path: "optional.tfvars", Backend: "local", Missing: true},
+		},
+		OutputSHA256: "out",
+	}
+
+	tests := []struct {
+		name    string
+		actual  []LockedVarsFile
+		output  string
+		wantErr []string
+	}{
+		{
+			name:   "unchanged, with new informational versions",
+			actual: []LockedVarsFile{{Path: "common.tfvars", Backend: "local", SHA256: "aaa"}, {Path: "common.tfvars", Backend: "git", Version: "blob2", SHA256: "bbb"}, {Path: "optional.tfvars", Backend: "local", Missing: true}},
+			output: "out",
+		},
+		{
+			name:    "same path changed on one backend",
+			actual:  []LockedVarsFile{{Path: "common.tfvars", Backend: "local", SHA256: "aaa"}, {Path: "common.tfvars", Backend: "git", SHA256: "ccc"}, {Path: "optional.tfvars", Backend: "local", Missing: true}},
+			output:  "out",
+			wantErr: []string{"common.tfvars (git) changed: sha256 ccc, locked bbb"},
+		},
+		{
+			name:    "file added and removed",
+			actual:  []LockedVarsFile{{Path: "common.tfvars", Backend: "local", SHA256: "aaa"}, {Path: "other.tfvars", Backend: "git", SHA256: "bbb"}, {Path: "optional.tfvars", Backend: "local", Missing: true}},
+			output:  "out",
+			wantErr: []string{"other.tfvars (git) is not in the lock", "common.tfvars (git) is locked but no longer read"},
+		},
+		{
+			name:    "optional file appeared",
+			actual:  []LockedVarsFile{{Path: "common.tfvars", Backend: "local", SHA256: "aaa"}, {Path: "common.tfvars", Backend: "git", SHA256: "bbb"}, {Path: "optional.tfvars", Backend: "local", SHA256: "ddd"}},
+			output:  "out",
+			wantErr: []string{"optional.tfvars (local) was missing when locked"},
+		},
+		{
+			name:    "output changed",
+			actual:  []LockedVarsFile{{Path: "common.tfvars", Backend: "local", SHA256: "aaa"}, {Path: "common.tfvars", Backend: "git", SHA256: "bbb"}, {Path: "optional.tfvars", Backend: "local", Missing: true}},
+			output:  "other",
+			wantErr: []string{"rendered tfvars changed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := locked.Verify(&EnvironmentLock{VarsFiles: tt.actual, OutputSHA256: tt.output})
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Verify() succeeded, want an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Verify() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLockVarsFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not found in PATH")
+	}
+
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	writeFile(t, filepath.Join(repo, "vars", "common.tfvars"), "region = \"eu-west-1\"\n")
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "v1"},
+	} {
+		if output, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+	blob, err := exec.Command("git", "-C", repo, "rev-parse", "HEAD:vars/common.tfvars").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	localFile := filepath.Join(dir, "local.tfvars")
+	writeFile(t, localFile, "replicas = 2\n")
+
+	cfg := loadTestConfig(t, `version: "1.1"
+secrets:
+  engine: sops
+environments:
+  - name: dev
+    vars_files:
+      - "`+filepath.ToSlash(localFile)+`"
+      - path: vars/common.tfvars
+        source:
+          type: git
+          config:
+            repository: "`+filepath.ToSlash(repo)+`"
+            ref: HEAD
+      - "`+filepath.ToSlash(filepath.Join(dir, "missing.tfvars"))+`?"
+`)
+
+	lock, variables, err := cfg.LockVarsFiles(context.Background(), "dev", dir)
+	if err != nil {
+		t.Fatalf("LockVarsFiles() error = %v", err)
+	}
+	if len(variables) != 2 {
+		t.Errorf("LockVarsFiles() returned %d variables, want 2", len(variables))
+	}
+
+	want := []LockedVarsFile{
+		{Path: filepath.ToSlash(localFile), Backend: "local"},
+		{Path: "vars/common.tfvars", Backend: "git", Version: strings.TrimSpace(string(blob))},
+		{Path: filepath.ToSlash(filepath.Join(dir, "missing.tfvars")), Backend: "local", Missing: true},
+	}
+	got := lock.VarsFiles
+	for i := range got {
+		// Checksums are covered by Verify; only check that files with content have one
+		if (got[i].SHA256 == "") != got[i].Missing {
+			t.Errorf("vars file %s has sha256 %q", got[i].Path, got[i].SHA256)
+		}
+		got[i].SHA256 = ""
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LockVarsFiles() = %+v, want %+v", got, want)
+	}
+	if err := lock.Verify(lock); err != nil {
+		t.Errorf("Verify() of the same lock error = %v", err)
+	}
+}
+
+func TestLockRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+	lock := NewLock()
+	lock.Environments["dev"] = &EnvironmentLock{
+		VarsFiles:    []LockedVarsFile{{Path: "common.tfvars", Backend: "local", SHA256: "aaa"}},
+		OutputSHA256: "out",
+	}
+	if err := lock.Write(path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	read, err := ReadLock(path)
+	if err != nil {
+		t.Fatalf("ReadLock() error = %v", err)
+	}
+	if !reflect.DeepEqual(read, lock) {
+		t.Errorf("ReadLock() = %+v, want %+v", read, lock)
+	}
+}
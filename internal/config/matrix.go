@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Matrix expands an environment template into one environment per combination of axis values
+type Matrix struct {
+	// Axes maps each axis name to its values. Combinations are generated in the
+	// order the axes are declared, with the last axis varying fastest.
+	Axes map[string][]string `yaml:"axes" schema:"required"`
+	// Exclude removes the combinations matching every value of an entry
+	Exclude []map[string]string `yaml:"exclude,omitempty"`
+	// Include adds combinations, which must set a value for every axis
+	Include []map[string]string `yaml:"include,omitempty"`
+}
+
+// matrixPattern matches ${matrix.AXIS} references. "$${...}" is left for interpolation to unescape.
+var matrixPattern = regexp.MustCompile(`\$?\$\{matrix\.([^}]*)\}`)
+
+// axisNamePattern matches valid axis names
+var axisNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// expandMatrices replaces every environment with a matrix in a parsed document
+// by its generated environments, substituting ${matrix.AXIS} in all of its keys and values.
+func expandMatrices(doc *yaml.Node) error {
+	environments := mappingValue(documentRoot(doc), "environments")
+	if environments == nil || environments.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	expanded := make([]*yaml.Node, 0, len(environments.Content))
+	for _, envNode := range environments.Content {
+		matrixNode := mappingValue(envNode, "matrix")
+		if matrixNode == nil {
+			expanded = append(expanded, envNode)
+			continue
+		}
+
+		generated, err := expandMatrix(envNode, matrixNode)
+		if err != nil {
+			return err
+		}
+		expanded = append(expanded, generated...)
+	}
+
+	environments.Content = expanded
+	return nil
+}
+
+// expandMatrix generates the environments of a single matrix template
+func expandMatrix(envNode, matrixNode *yaml.Node) ([]*yaml.Node, error) {
+	var matrix Matrix
+	if err := matrixNode.Decode(&matrix); err != nil {
+		return nil, fmt.Errorf("line %d, column %d: invalid matrix: %w", matrixNode.Line, matrixNode.Column, err)
+	}
+
+	// Read the axis names from the node to keep their declared order
+	var axes []string
+	if axesNode := mappingValue(matrixNode, "axes"); axesNode != nil && axesNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(axesNode.Content); i += 2 {
+			axes = append(axes, axesNode.Content[i].Value)
+		}
+	}
+
+	combinations, err := matrixCombinations(axes, &matrix)
+	if err != nil {
+		return nil, fmt.Errorf("line %d, column %d: invalid matrix: %w", matrixNode.Line, matrixNode.Column, err)
+	}
+
+	nameNode := mappingValue(envNode, "name")
+	if nameNode == nil || !matrixPattern.MatchString(nameNode.Value) {
+		return nil, fmt.Errorf("line %d, column %d: environment with a matrix must reference an axis in its name (e.g. \"app-${matrix.%s}\")",
+			envNode.Line, envNode.Column, axes[0])
+	}
+
+	generated := make([]*yaml.Node, 0, len(combinations))
+	for _, values := range combinations {
+		node := cloneNode(envNode)
+
+		// Drop the matrix key from the generated environment
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "matrix" {
+				node.Content = append(node.Content[:i], node.Content[i+2:]...)
+				break
+			}
+		}
+
+		if err := substituteMatrix(node, values); err != nil {
+			return nil, err
+		}
+		generated = append(generated, node)
+	}
+
+	return generated, nil
+}
+
+// matrixCombinations returns the combinations of axis values, minus exclusions, plus inclusions
+func matrixCombinations(axes []string, matrix *Matrix) ([]map[string]string, error) {
+	if len(axes) == 0 {
+		return nil, fmt.Errorf("axes must define at least one axis")
+	}
+	for _, axis := range axes {
+		if !axisNamePattern.MatchString(axis) {
+			return nil, fmt.Errorf("invalid axis name %q", axis)
+		}
+		if len(matrix.Axes[axis]) == 0 {
+			return nil, fmt.Errorf("axis %s has no values", axis)
+		}
+	}
+
+	checkKeys := func(entry map[string]string, field string, i int) error {
+		for key := range entry {
+			if _, ok := matrix.Axes[key]; !ok {
+				return fmt.Errorf("%s[%d] refers to unknown axis %q", field, i, key)
+			}
+		}
+		return nil
+	}
+
+	combinations := []map[string]string{{}}
+	for _, axis := range axes {
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, value := range matrix.Axes[axis] {
+				extended := make(map[string]string, len(combination)+1)
+				for k, v := range combination {
+					extended[k] = v
+				}
+				extended[axis] = value
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+
+	for i, exclude := range matrix.Exclude {
+		if err := checkKeys(exclude, "exclude", i); err != nil {
+			return nil, err
+		}
+	}
+	var result []map[string]string
+	for _, combination := range combinations {
+		excluded := false
+		for _, exclude := range matrix.Exclude {
+			if matchesCombination(combination, exclude) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			result = append(result, combination)
+		}
+	}
+
+	for i, include := range matrix.Include {
+		if err := checkKeys(include, "include", i); err != nil {
+			return nil, err
+		}
+		if len(include) != len(axes) {
+			return nil, fmt.Errorf("include[%d] must set a value for every axis (%s)", i, strings.Join(axes, ", "))
+		}
+		duplicate := false
+		for _, combination := range result {
+			if matchesCombination(combination, include) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, include)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("every combination is excluded")
+	}
+
+	return result, nil
+}
+
+// matchesCombination reports whether combination has every value of entry
+func matchesCombination(combination, entry map[string]string) bool {
+	for key, value := range entry {
+		if combination[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// substituteMatrix replaces ${matrix.AXIS} references in every scalar of node
+func substituteMatrix(node *yaml.Node, values map[string]string) error {
+	if node.Kind == yaml.ScalarNode {
+		var firstErr error
+		node.Value = matrixPattern.ReplaceAllStringFunc(node.Value, func(match string) string {
+			if strings.HasPrefix(match, "$$") {
+				return match
+			}
+			axis := matrixPattern.FindStringSubmatch(match)[1]
+			value, ok := values[axis]
+			if !ok && firstErr == nil {
+				firstErr = fmt.Errorf("line %d, column %d: %s: unknown matrix axis %q", node.Line, node.Column, match, axis)
+			}
+			return value
+		})
+		return firstErr
+	}
+
+	for _, child := range node.Content {
+		if err := substituteMatrix(child, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cloneNode returns a deep copy of node
+func cloneNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+
+	clone := *node
+	clone.Alias = cloneNode(node.Alias)
+	if node.Content != nil {
+		clone.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			clone.Content[i] = cloneNode(child)
+		}
+	}
+	return &clone
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LatestVersion is the config file version written by tivor init and tivor migrate
+const LatestVersion = "1.1"
+
+// Deprecation describes a deprecated construct found while reading an older config version
+type Deprecation struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// textEdit replaces a token of the original file, located by line and column
+type textEdit struct {
+	line   int
+	column int
+	old    string
+	new    string
+}
+
+// migration upgrades a config document from one version to the next
+type migration struct {
+	from  string
+	to    string
+	apply func(root *yaml.Node) ([]Deprecation, []textEdit)
+}
+
+// migrations lists every upgrade step in order
+var migrations = []migration{
+	{from: "1.0", to: "1.1", apply: migrate10To11},
+}
+
+// migrateNode upgrades a parsed config document from version to LatestVersion in place.
+// The version key of the document is updated only if it is present.
+// The returned edits reproduce the changes on the original text.
+func migrateNode(doc *yaml.Node, version string) ([]Deprecation, []textEdit, error) {
+	root := documentRoot(doc)
+	if root == nil {
+		return nil, nil, nil
+	}
+
+	var deprecations []Deprecation
+	var edits []textEdit
+	for _, m := range migrations {
+		if version != m.from {
+			continue
+		}
+		d, e := m.apply(root)
+		deprecations = append(deprecations, d...)
+		edits = append(edits, e...)
+		version = m.to
+	}
+
+	if version != LatestVersion {
+		return nil, nil, fmt.Errorf("no migration path from version %q to %q", version, LatestVersion)
+	}
+
+	if versionNode := mappingValue(root, "version"); versionNode != nil {
+		edits = append(edits, replaceScalar(versionNode, LatestVersion))
+	}
+
+	return deprecations, edits, nil
+}
+
+// replaceScalar sets the value of a scalar node and returns the matching text edit.
+func replaceScalar(node *yaml.Node, value string) textEdit {
+	quote := ""
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		quote = `"`
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		quote = "'"
+	}
+
+	edit := textEdit{
+		line:   node.Line,
+		column: node.Column,
+		old:    quote + node.Value + quote,
+		new:    quote + value + quote,
+	}
+	node.Value = value
+	return edit
+}
+
+// migrate10To11 renames environments[].backend to vars_backend, which cannot be
+// mistaken for the Terraform state backend.
+func migrate10To11(root *yaml.Node) ([]Deprecation, []textEdit) {
+	var deprecations []Deprecation
+	var edits []textEdit
+
+	environments := mappingValue(root, "environments")
+	if environments == nil || environments.Kind != yaml.SequenceNode {
+		return nil, nil
+	}
+
+	for _, env := range environments.Content {
+		if env.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(env.Content); i += 2 {
+			key := env.Content[i]
+			if key.Value != "backend" {
+				continue
+			}
+			deprecations = append(deprecations, Deprecation{
+				Line:    key.Line,
+				Column:  key.Column,
+				Message: `"backend" is deprecated, use "vars_backend"`,
+			})
+			edits = append(edits, replaceScalar(key, "vars_backend"))
+		}
+	}
+
+	return deprecations, edits
+}
+
+// documentVersion returns the version declared in a parsed config document, if any.
+func documentVersion(doc *yaml.Node) string {
+	if versionNode := mappingValue(documentRoot(doc), "version"); versionNode != nil {
+		return versionNode.Value
+	}
+	return ""
+}
+
+// documentRoot returns the top-level mapping of a parsed document.
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc == nil {
+		return nil
+	}
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc
+}
+
+// mappingValue returns the value node for key in a mapping node.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// MigratedFile is the result of upgrading a single config file
+type MigratedFile struct {
+	Path         string
+	FromVersion  string
+	Content      []byte
+	Changed      bool
+	Deprecations []Deprecation
+}
+
+// Migrate upgrades the config file at path and every file it includes to LatestVersion.
+// Comments and key order are preserved. Nothing is written to disk.
+func Migrate(path string) ([]MigratedFile, error) {
+	var files []MigratedFile
+	if err := migrateFile(path, "", map[string]bool{}, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// migrateFile upgrades a single file and recurses into its includes.
+func migrateFile(path, defaultVersion string, visited map[string]bool, files *[]MigratedFile) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config file path (%s): %w", path, err)
+	}
+	if visited[absPath] {
+		return nil
+	}
+	visited[absPath] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file (%s): %w", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file (%s): %w", path, err)
+	}
+
+	version := documentVersion(&doc)
+	if version == "" {
+		version = defaultVersion
+	}
+	if version == "" {
+		return fmt.Errorf("config file (%s) has no version", path)
+	}
+	if !isSupportedVersion(version) {
+		return fmt.Errorf("config file (%s) has unsupported version %q (supported versions: %s)", path, version, strings.Join(SupportedVersions, ", "))
+	}
+
+	file := MigratedFile{Path: path, FromVersion: version, Content: data}
+	if version != LatestVersion {
+		var edits []textEdit
+		file.Deprecations, edits, err = migrateNode(&doc, version)
+		if err != nil {
+			return fmt.Errorf("failed to upgrade config file (%s): %w", path, err)
+		}
+
+		// Edit the original text so formatting and blank lines survive;
+		// re-encode the document only if the edits cannot be applied
+		if content, ok := applyEdits(data, edits); ok {
+			file.Content = content
+		} else {
+			var buf bytes.Buffer
+			encoder := yaml.NewEncoder(&buf)
+			encoder.SetIndent(2)
+			if err := encoder.Encode(&doc); err != nil {
+				return fmt.Errorf("failed to encode config file (%s): %w", path, err)
+			}
+			if err := encoder.Close(); err != nil {
+				return fmt.Errorf("failed to encode config file (%s): %w", path, err)
+			}
+			file.Content = buf.Bytes()
+		}
+		file.Changed = true
+	}
+	*files = append(*files, file)
+
+	// Included files without a version follow the version of the including file
+	include := mappingValue(documentRoot(&doc), "include")
+	if include == nil || include.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, pattern := range include.Content {
+		matches, err := expandInclude(filepath.Dir(path), pattern.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, match := range matches {
+			if err := migrateFile(match, version, visited, files); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyEdits applies text edits to data. It reports false if any edit does not
+// match the original text, e.g. for multi-line scalars.
+func applyEdits(data []byte, edits []textEdit) ([]byte, bool) {
+	lines := strings.SplitAfter(string(data), "\n")
+
+	// Apply right-to-left so earlier columns on the same line stay valid
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line < edits[j].line
+		}
+		return edits[i].column > edits[j].column
+	})
+
+	for _, edit := range edits {
+		if edit.line < 1 || edit.line > len(lines) {
+			return nil, false
+		}
+		line := []rune(lines[edit.line-1])
+		start := edit.column - 1
+		old := []rune(edit.old)
+		if start < 0 || start+len(old) > len(line) || string(line[start:start+len(old)]) != edit.old {
+			return nil, false
+		}
+		lines[edit.line-1] = string(line[:start]) + edit.new + string(line[start+len(old):])
+	}
+
+	return []byte(strings.Join(lines, "")), true
+}
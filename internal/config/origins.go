@@ -0,0 +1,116 @@
+package config
+
+import "fmt"
+
+// DefaultsOrigin names the defaults block in field origins
+const DefaultsOrigin = "defaults"
+
+// InheritanceChain returns the environment name followed by its ancestors, nearest first.
+func (c *Config) InheritanceChain(name string) ([]string, error) {
+	var chain []string
+	seen := make(map[string]bool)
+
+	for current := name; current != ""; {
+		if seen[current] {
+			return nil, fmt.Errorf("inheritance cycle detected at environment %s", current)
+		}
+		seen[current] = true
+
+		env, err := c.GetEnvironment(current)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, current)
+		current = env.Inherits
+	}
+
+	return chain, nil
+}
+
+// FieldOrigins reports, for every field of the resolved environment, where its value comes from.
+// Each entry lists the contributing environments (nearest first) and "defaults".
+// Overriding fields have a single contributor; merged fields such as vars_files may have several.
+func (c *Config) FieldOrigins(name string) (map[string][]string, error) {
+	chain, err := c.InheritanceChain(name)
+	if err != nil {
+		return nil, err
+	}
+
+	origins := make(map[string][]string)
+	add := func(field, origin string, set bool) {
+		if set {
+			origins[field] = append(origins[field], origin)
+		}
+	}
+	// override records only the nearest contributor
+	override := func(field, origin string, set bool) {
+		if set && len(origins[field]) == 0 {
+			origins[field] = []string{origin}
+		}
+	}
+
+	for i, envName := range chain {
+		env, err := c.GetEnvironment(envName)
+		if err != nil {
+			return nil, err
+		}
+
+		// Fields that are never inherited come only from the environment itself
+		if i == 0 {
+			add("workspace", envName, env.Workspace != "")
+			add("protected", envName, env.Protected)
+		}
+
+		add("tags", envName, len(env.Tags) > 0)
+		add("vars_files", envName, len(env.VarsFiles) > 0)
+		add("vars", envName, len(env.Vars) > 0)
+		add("sensitive", envName, len(env.Sensitive) > 0)
+		add("execution", envName, env.Execution != nil)
+		add("hooks", envName, env.Hooks != nil)
+		override("vars_backend", envName, env.Backend != nil)
+		override("working_dir", envName, env.WorkingDir != "")
+		override("plan_args", envName, len(env.PlanArgs) > 0)
+		override("apply_args", envName, len(env.ApplyArgs) > 0)
+	}
+
+	if d := c.Defaults; d != nil {
+		add("vars_files", DefaultsOrigin, len(d.VarsFiles) > 0)
+		add("vars", DefaultsOrigin, len(d.Vars) > 0)
+		add("sensitive", DefaultsOrigin, len(d.Sensitive) > 0)
+		add("execution", DefaultsOrigin, d.Execution != nil)
+		add("hooks", DefaultsOrigin, d.Hooks != nil)
+	}
+
+	return origins, nil
+}
+
+// VarOrigins reports, for every inline variable of the resolved environment, the nearest
+// environment of the inheritance chain or "defaults" whose vars block sets it.
+func (c *Config) VarOrigins(name string) (map[string]string, error) {
+	chain, err := c.InheritanceChain(name)
+	if err != nil {
+		return nil, err
+	}
+
+	origins := make(map[string]string)
+	set := func(vars map[string]interface{}, origin string) {
+		for varName := range vars {
+			if _, ok := origins[varName]; !ok {
+				origins[varName] = origin
+			}
+		}
+	}
+
+	for _, envName := range chain {
+		env, err := c.GetEnvironment(envName)
+		if err != nil {
+			return nil, err
+		}
+		set(env.Vars, envName)
+	}
+	if c.Defaults != nil {
+		set(c.Defaults.Vars, DefaultsOrigin)
+	}
+
+	return origins, nil
+}
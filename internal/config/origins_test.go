@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProtectedNotInherited(t *testing.T) {
+	cfg := loadTestConfig(t, `version: "1.1"
+environments:
+  - name: dev
+  - name: production
+    protected: true
+  - name: production-eu
+    inherits: production
+`)
+
+	tests := []struct {
+		env           string
+		wantProtected bool
+		wantOrigins   []string
+	}{
+		{env: "dev", wantProtected: false},
+		{env: "production", wantProtected: true, wantOrigins: []string{"production"}},
+		{env: "production-eu", wantProtected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			env, err := cfg.ResolveEnvironment(tt.env)
+			if err != nil {
+				t.Fatalf("ResolveEnvironment() error = %v", err)
+			}
+			if env.Protected != tt.wantProtected {
+				t.Errorf("Protected = %v, want %v", env.Protected, tt.wantProtected)
+			}
+
+			origins, err := cfg.FieldOrigins(tt.env)
+			if err != nil {
+				t.Fatalf("FieldOrigins() error = %v", err)
+			}
+			if !reflect.DeepEqual(origins["protected"], tt.wantOrigins) {
+				t.Errorf("origins[protected] = %v, want %v", origins["protected"], tt.wantOrigins)
+			}
+		})
+	}
+}
+
+func TestVariableSources(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "tivor.yaml"), `version: "1.1"
+include:
+  - envs/production.yaml
+defaults:
+  vars:
+    owner: platform
+    size: 1
+environments:
+  - name: staging
+    vars:
+      size: 2
+      region: eu
+`)
+	writeFile(t, filepath.Join(root, "envs", "production.yaml"), `defaults:
+  vars:
+    team: payments
+environments:
+  - name: production
+    inherits: staging
+    vars:
+      region: us
+`)
+	chdir(t, root)
+	cfg, err := LoadConfig("tivor.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		env  string
+		want map[string]string
+	}{
+		{
+			env: "staging",
+			want: map[string]string{
+				"owner":  "tivor.yaml (vars of defaults)",
+				"region": "tivor.yaml (vars of staging)",
+				"size":   "tivor.yaml (vars of staging)",
+				"team":   "envs/production.yaml (vars of defaults)",
+			},
+		},
+		{
+			env: "production",
+			want: map[string]string{
+				"owner":  "tivor.yaml (vars of defaults)",
+				"region": "envs/production.yaml (vars of production)",
+				"size":   "tivor.yaml (vars of staging)",
+				"team":   "envs/production.yaml (vars of defaults)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			variables, err := cfg.ResolveVariables(context.Background(), tt.env, "")
+			if err != nil {
+				t.Fatalf("ResolveVariables() error = %v", err)
+			}
+			got := make(map[string]string)
+			for _, variable := range variables {
+				got[variable.Name] = variable.Source
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sources = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
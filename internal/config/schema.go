@@ -0,0 +1,159 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// durationPattern matches the durations accepted by time.ParseDuration
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+// JSONSchema returns a JSON Schema describing tivor.yaml, generated from the config types.
+func JSONSchema() ([]byte, error) {
+	g := &schemaGenerator{defs: make(map[string]interface{})}
+	root := g.schemaFor(reflect.TypeOf(Config{}))
+
+	// Restrict version to the versions this release can read
+	config := g.defs["Config"].(map[string]interface{})
+	config["properties"].(map[string]interface{})["version"] = map[string]interface{}{
+		"type": "string",
+		"enum": SupportedVersions,
+	}
+
+	// Version 1.0 files name vars_backend backend, which the loader renames
+	g.defineVersion10()
+
+	schema := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     "https://github.com/marcy326/tivor/tivor.schema.json",
+		"title":   "tivor configuration",
+		"if": map[string]interface{}{
+			"properties": map[string]interface{}{"version": map[string]interface{}{"const": "1.0"}},
+			"required":   []string{"version"},
+		},
+		"then":  map[string]interface{}{"$ref": "#/$defs/" + config10Def},
+		"else":  root,
+		"$defs": g.defs,
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaGenerator builds schemas for Go types, collecting struct definitions in defs
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+// schemaFor returns the schema of t, referencing struct types through $defs
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeOf(time.Duration(0)):
+		return map[string]interface{}{"type": "string", "pattern": durationPattern}
+	case reflect.TypeOf(VarsFile{}):
+		// vars_files entries accept a path string or a mapping
+		return map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				g.structRef(t),
+			},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		return g.structRef(t)
+	default:
+		// interface{} accepts any value
+		return map[string]interface{}{}
+	}
+}
+
+// structRef defines a struct type in $defs once and returns a reference to it
+func (g *schemaGenerator) structRef(t reflect.Type) map[string]interface{} {
+	ref := map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	if _, ok := g.defs[t.Name()]; ok {
+		return ref
+	}
+
+	properties := make(map[string]interface{})
+	definition := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	// Register before recursing so self-referencing types terminate
+	g.defs[t.Name()] = definition
+
+	var required []string
+	for _, field := range yamlFields(t) {
+		properties[field.Name] = g.schemaFor(field.Type)
+		if field.Required {
+			required = append(required, field.Name)
+		}
+	}
+	if len(required) > 0 {
+		definition["required"] = required
+	}
+
+	return ref
+}
+
+// Definitions of the version 1.0 variants of Config and Environment
+const (
+	config10Def      = "Config_1_0"
+	environment10Def = "Environment_1_0"
+)
+
+// defineVersion10 defines the version 1.0 variants of Config and Environment,
+// which also accept environments[].backend
+func (g *schemaGenerator) defineVersion10() {
+	environment := copyDefinition(g.defs["Environment"].(map[string]interface{}))
+	properties := environment["properties"].(map[string]interface{})
+	properties["backend"] = map[string]interface{}{
+		"$ref":        "#/$defs/Backend",
+		"description": "Vars backend; renamed to vars_backend in version 1.1",
+		"deprecated":  true,
+	}
+	g.defs[environment10Def] = environment
+
+	config := copyDefinition(g.defs["Config"].(map[string]interface{}))
+	properties = config["properties"].(map[string]interface{})
+	properties["version"] = map[string]interface{}{"type": "string", "const": "1.0"}
+	properties["environments"] = map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"$ref": "#/$defs/" + environment10Def},
+	}
+	g.defs[config10Def] = config
+}
+
+// copyDefinition copies a struct definition and its properties
+func copyDefinition(definition map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(definition))
+	for key, value := range definition {
+		copied[key] = value
+	}
+	properties := make(map[string]interface{})
+	for key, value := range definition["properties"].(map[string]interface{}) {
+		properties[key] = value
+	}
+	copied["properties"] = properties
+	return copied
+}
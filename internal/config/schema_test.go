@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema() error = %v", err)
+	}
+	var schema struct {
+		If   map[string]interface{} `json:"if"`
+		Then map[string]interface{} `json:"then"`
+		Else map[string]interface{} `json:"else"`
+		Defs map[string]struct {
+			Properties map[string]interface{} `json:"properties"`
+			Required   []string               `json:"required"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("invalid schema JSON: %v", err)
+	}
+
+	if schema.Then["$ref"] != "#/$defs/Config_1_0" || schema.Else["$ref"] != "#/$defs/Config" {
+		t.Errorf("then = %v, else = %v, want the 1.0 and latest configs", schema.Then, schema.Else)
+	}
+
+	tests := []struct {
+		def          string
+		wantRequired []string
+		wantBackend  bool
+	}{
+		// Included files may omit version and environments
+		{def: "Config"},
+		{def: "Config_1_0"},
+		{def: "Defaults"},
+		{def: "Environment", wantRequired: []string{"name"}},
+		{def: "Environment_1_0", wantRequired: []string{"name"}, wantBackend: true},
+		{def: "Backend", wantRequired: []string{"type"}},
+		{def: "VarsFile", wantRequired: []string{"path"}},
+		{def: "Hook", wantRequired: []string{"command"}},
+		{def: "Matrix", wantRequired: []string{"axes"}},
+		{def: "Secrets", wantRequired: []string{"engine"}},
+		{def: "Execution"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.def, func(t *testing.T) {
+			def, ok := schema.Defs[tt.def]
+			if !ok {
+				t.Fatalf("definition %s is missing", tt.def)
+			}
+			if !reflect.DeepEqual(def.Required, tt.wantRequired) {
+				t.Errorf("required = %v, want %v", def.Required, tt.wantRequired)
+			}
+			if _, ok := def.Properties["backend"]; ok != tt.wantBackend {
+				t.Errorf("backend property present = %v, want %v", ok, tt.wantBackend)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// selectorOperator is the comparison made by a selector requirement
+type selectorOperator string
+
+const (
+	selectorExists    selectorOperator = "exists"
+	selectorNotExists selectorOperator = "!"
+	selectorEquals    selectorOperator = "="
+	selectorNotEquals selectorOperator = "!="
+	selectorIn        selectorOperator = "in"
+	selectorNotIn     selectorOperator = "notin"
+)
+
+// requirement is a single condition of a selector
+type requirement struct {
+	key      string
+	operator selectorOperator
+	values   []string
+}
+
+// Selector matches environments by their tags.
+// All requirements must match.
+type Selector struct {
+	requirements []requirement
+}
+
+// ParseSelector parses a comma-separated list of requirements:
+//
+//	key            the tag is present
+//	!key           the tag is absent
+//	key=value      the tag has the value (also key==value)
+//	key!=value     the tag is absent or has another value
+//	key in (a,b)   the tag has one of the values
+//	key notin (a,b) the tag is absent or has none of the values
+func ParseSelector(s string) (*Selector, error) {
+	parts, err := splitSelector(s)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := &Selector{}
+	for _, part := range parts {
+		req, err := parseRequirement(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", s, err)
+		}
+		selector.requirements = append(selector.requirements, req)
+	}
+
+	if len(selector.requirements) == 0 {
+		return nil, fmt.Errorf("selector is empty")
+	}
+
+	return selector, nil
+}
+
+// Matches reports whether tags satisfy every requirement of the selector
+func (s *Selector) Matches(tags map[string]string) bool {
+	for _, req := range s.requirements {
+		if !req.matches(tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether tags satisfy the requirement
+func (r requirement) matches(tags map[string]string) bool {
+	value, ok := tags[r.key]
+
+	switch r.operator {
+	case selectorExists:
+		return ok
+	case selectorNotExists:
+		return !ok
+	case selectorEquals:
+		return ok && value == r.values[0]
+	case selectorNotEquals:
+		return !ok || value != r.values[0]
+	case selectorIn:
+		return ok && slices.Contains(r.values, value)
+	case selectorNotIn:
+		return !ok || !slices.Contains(r.values, value)
+	}
+	return false
+}
+
+// SelectEnvironments returns the names of the environments whose tags, including
+// inherited ones, match the selector, in configuration order
+func (c *Config) SelectEnvironments(selector *Selector) ([]string, error) {
+	var names []string
+	for _, env := range c.Environments {
+		resolved, err := c.resolveInheritance(env.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve environment %s: %w", env.Name, err)
+		}
+		if selector.Matches(TagMap(resolved.Tags)) {
+			names = append(names, env.Name)
+		}
+	}
+	return names, nil
+}
+
+// splitSelector splits a selector on commas that are not inside parentheses
+func splitSelector(s string) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("invalid selector %q: unbalanced parentheses", s)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("invalid selector %q: unbalanced parentheses", s)
+	}
+	parts = append(parts, s[start:])
+
+	var result []string
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid selector %q: empty requirement", s)
+		}
+		result = append(result, part)
+	}
+	return result, nil
+}
+
+// parseRequirement parses a single selector requirement
+func parseRequirement(s string) (requirement, error) {
+	if key, ok := strings.CutPrefix(s, "!"); ok {
+		key = strings.TrimSpace(key)
+		if err := validateSelectorTerm(key); err != nil {
+			return requirement{}, err
+		}
+		return requirement{key: key, operator: selectorNotExists}, nil
+	}
+
+	if key, value, ok := strings.Cut(s, "!="); ok {
+		return newRequirement(key, selectorNotEquals, value)
+	}
+	if key, value, ok := strings.Cut(s, "=="); ok {
+		return newRequirement(key, selectorEquals, value)
+	}
+	if key, value, ok := strings.Cut(s, "="); ok {
+		return newRequirement(key, selectorEquals, value)
+	}
+
+	if fields := strings.Fields(s); len(fields) > 1 {
+		key := fields[0]
+		rest := strings.TrimSpace(strings.TrimPrefix(s, key))
+		for _, operator := range []selectorOperator{selectorNotIn, selectorIn} {
+			if list, ok := strings.CutPrefix(rest, string(operator)); ok {
+				return newSetRequirement(key, operator, strings.TrimSpace(list))
+			}
+		}
+		return requirement{}, fmt.Errorf("unknown operator in %q (expected =, !=, in or notin)", s)
+	}
+
+	if err := validateSelectorTerm(s); err != nil {
+		return requirement{}, err
+	}
+	return requirement{key: s, operator: selectorExists}, nil
+}
+
+// newRequirement creates an equality or inequality requirement
+func newRequirement(key string, operator selectorOperator, value string) (requirement, error) {
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if err := validateSelectorTerm(key); err != nil {
+		return requirement{}, err
+	}
+	if err := validateSelectorTerm(value); err != nil {
+		return requirement{}, err
+	}
+	return requirement{key: key, operator: operator, values: []string{value}}, nil
+}
+
+// newSetRequirement creates an in or notin requirement from a parenthesized list
+func newSetRequirement(key string, operator selectorOperator, list string) (requirement, error) {
+	if !strings.HasPrefix(list, "(") || !strings.HasSuffix(list, ")") {
+		return requirement{}, fmt.Errorf("values of %s %s must be in parentheses", key, operator)
+	}
+	if err := validateSelectorTerm(key); err != nil {
+		return requirement{}, err
+	}
+
+	var values []string
+	for _, value := range strings.Split(list[1:len(list)-1], ",") {
+		value = strings.TrimSpace(value)
+		if err := validateSelectorTerm(value); err != nil {
+			return requirement{}, err
+		}
+		values = append(values, value)
+	}
+	return requirement{key: key, operator: operator, values: values}, nil
+}
+
+// validateSelectorTerm checks that a key or value in a selector is a valid tag key or value
+func validateSelectorTerm(term string) error {
+	if !tagKeyPattern.MatchString(term) {
+		return fmt.Errorf("invalid key or value %q", term)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/marcy326/tivor/internal/terraform"
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+// SensitiveNames returns the names of the sensitive variables of a resolved environment:
+// those listed under sensitive and those declared with sensitive = true in the .tf files
+// of its working directory. Variables read from secret stores are sensitive regardless.
+// A non-empty workingDir overrides the working directory of the environment, as --working-dir does.
+func SensitiveNames(env *Environment, workingDir string) (map[string]bool, error) {
+	names := make(map[string]bool)
+	for _, name := range env.Sensitive {
+		names[name] = true
+	}
+
+	if workingDir == "" {
+		workingDir = env.WorkingDir
+	}
+	if workingDir == "" {
+		workingDir = "."
+	}
+	declared, err := terraform.SensitiveVariables(workingDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sensitive variable declarations: %w", err)
+	}
+	for _, name := range declared {
+		names[name] = true
+	}
+
+	return names, nil
+}
+
+// secretValues returns the texts a sensitive value can appear as in output: its tfvars
+// literal and its string and number leaves, as Terraform prints the fields of objects,
+// maps and lists one by one
+func secretValues(variable tfvars.Variable) []string {
+	values := []string{variable.Value}
+	if variable.Type == tfvars.BoolType {
+		return values
+	}
+	for _, leaf := range tfvars.Leaves(variable.Value) {
+		if leaf != variable.Value {
+			values = append(values, leaf)
+		}
+	}
+	return values
+}
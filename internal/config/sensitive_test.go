@@ -0,0 +1,154 @@
+package config
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/marcy326/tivor/internal/terraform"
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+func TestSensitiveNames(t *testing.T) {
+	envDir := t.TempDir()
+	overrideDir := t.TempDir()
+	writeFile(t, filepath.Join(envDir, "variables.tf"), "variable \"env_secret\" {\n  sensitive = true\n}\n")
+	writeFile(t, filepath.Join(overrideDir, "variables.tf"), "variable \"override_secret\" {\n  type      = string\n  sensitive = true # from -w\n}\nvariable \"plain\" {}\n")
+
+	tests := []struct {
+		name       string
+		env        *Environment
+		workingDir string
+		want       map[string]bool
+	}{
+		{
+			name: "listed names",
+			env:  &Environment{Sensitive: []string{"api_key"}, WorkingDir: filepath.Join(envDir, "missing")},
+			want: map[string]bool{"api_key": true},
+		},
+		{
+			name: "environment working dir",
+			env:  &Environment{Sensitive: []string{"api_key"}, WorkingDir: envDir},
+			want: map[string]bool{"api_key": true, "env_secret": true},
+		},
+		{
+			name:       "working dir override",
+			env:        &Environment{WorkingDir: envDir},
+			workingDir: overrideDir,
+			want:       map[string]bool{"override_secret": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SensitiveNames(tt.env, tt.workingDir)
+			if err != nil {
+				t.Fatalf("SensitiveNames() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SensitiveNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretValues(t *testing.T) {
+	tests := []struct {
+		variable tfvars.Variable
+		want     []string
+	}{
+		{variable: tfvars.Variable{Value: `"a-$${b}"`, Type: tfvars.StringType}, want: []string{`"a-$${b}"`, "a-${b}"}},
+		{variable: tfvars.Variable{Value: "1234", Type: tfvars.NumberType}, want: []string{"1234"}},
+		{variable: tfvars.Variable{Value: "true", Type: tfvars.BoolType}, want: []string{"true"}},
+		{
+			variable: tfvars.Variable{Value: "{\n  password = \"hunter22\"\n  port = 5432\n}", Type: tfvars.ObjectType},
+			want:     []string{"{\n  password = \"hunter22\"\n  port = 5432\n}", "hunter22", "5432"},
+		},
+		{
+			variable: tfvars.Variable{Value: `["token-a", "token-b"]`, Type: tfvars.ArrayType},
+			want:     []string{`["token-a", "token-b"]`, "token-a", "token-b"},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := secretValues(tt.variable); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("secretValues(%s) = %q, want %q", tt.variable.Value, got, tt.want)
+		}
+	}
+}
+
+func TestSensitiveObjectFieldRedactedFromOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as terraform")
+	}
+
+	cfg := loadTestConfig(t, `version: "1.1"
+environments:
+  - name: prod
+    sensitive: [db]
+    vars:
+      db:
+        user: admin
+        password: obj-field-secret
+`)
+	if _, err := cfg.ResolveVariables(context.Background(), "prod", t.TempDir()); err != nil {
+		t.Fatalf("ResolveVariables() error = %v", err)
+	}
+
+	// Terraform prints the fields of an object one by one
+	bin := t.TempDir()
+	writeFile(t, filepath.Join(bin, "terraform"), "#!/bin/sh\necho '  ~ db = {'\necho '      password = \"obj-field-secret\"'\necho '  }'\n")
+	if err := os.Chmod(filepath.Join(bin, "terraform"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	output := captureStdout(t, func() {
+		if err := terraform.NewExecutor(t.TempDir(), "").Plan(context.Background()); err != nil {
+			t.Fatalf("Plan() error = %v", err)
+		}
+	})
+	if strings.Contains(output, "obj-field-secret") {
+		t.Errorf("output contains the sensitive field:\n%s", output)
+	}
+	if !strings.Contains(output, `password = "(sensitive)"`) {
+		t.Errorf("output = %q, want the field redacted", output)
+	}
+}
+
+// captureStdout returns what f writes to os.Stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		done <- string(data)
+	}()
+	f()
+	writer.Close()
+	return <-done
+}
+
+// writeFile writes content to path, creating its directory
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,458 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcy326/tivor/internal/backend"
+	"github.com/marcy326/tivor/internal/backend/cache"
+	"github.com/marcy326/tivor/internal/backend/git"
+	httpbackend "github.com/marcy326/tivor/internal/backend/http"
+	"github.com/marcy326/tivor/internal/backend/local"
+	"github.com/marcy326/tivor/internal/backend/vault"
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+// maxConcurrentFetches limits how many vars files are fetched at the same time
+const maxConcurrentFetches = 8
+
+// varsSource is a concrete vars file together with the backend it is read from
+type varsSource struct {
+	// VarsFile holds the path within the backend
+	VarsFile
+	// Label is the path as written in tivor.yaml, used in messages and provenance
+	Label string
+	// Type is the type of the backend
+	Type    string
+	Backend backend.Backend
+	// backendKey identifies the backend among the environment's vars sources
+	backendKey string
+}
+
+// key identifies the file a vars source reads
+func (v varsSource) key() string {
+	return v.backendKey + "\x00" + v.Path
+}
+
+// SetCache makes remote vars backends read through the cache.
+// In offline mode, their files are served only from the cache.
+func (c *Config) SetCache(varsCache *cache.Cache, offline bool) {
+	c.cache = varsCache
+	c.offline = offline
+}
+
+// varsSources creates the backends of an environment's vars files.
+// Entries with the same source share one backend.
+type varsSources struct {
+	config         *Config
+	defaultType    string
+	defaultBackend backend.Backend
+	// localRoot is the absolute path set on a local default backend, if any
+	localRoot string
+	backends  map[string]backend.Backend
+}
+
+// newVarsSources creates the vars sources of a resolved environment
+func (c *Config) newVarsSources(env *Environment) (*varsSources, error) {
+	defaultBackend, err := NewBackend(env)
+	if err != nil {
+		return nil, err
+	}
+	if env.Backend != nil {
+		defaultBackend = c.withCache(defaultBackend, env.Backend)
+	}
+
+	sources := &varsSources{
+		config:         c,
+		defaultType:    env.BackendType(),
+		defaultBackend: defaultBackend,
+		backends:       make(map[string]backend.Backend),
+	}
+	if env.Backend != nil && env.Backend.Type == "local" {
+		if root, _ := env.Backend.localConfig()["path"].(string); root != "" {
+			if sources.localRoot, err = filepath.Abs(root); err != nil {
+				return nil, fmt.Errorf("failed to resolve local backend path %s: %w", root, err)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// withCache wraps a remote backend with the cache, if one is set
+func (c *Config) withCache(backendInstance backend.Backend, config *Backend) backend.Backend {
+	if c.cache == nil || config.Type == "local" {
+		return backendInstance
+	}
+	return c.cache.Wrap(backendInstance, config.Type, backendID(config), backendLocation(config), c.offline)
+}
+
+// backendID identifies a backend configuration by a hash, as the configuration may
+// contain credentials. fmt prints maps with sorted keys, so equal configurations have the same ID.
+func backendID(config *Backend) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%v", config.Config)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// backendLocation describes where a backend reads from, without credentials
+func backendLocation(config *Backend) string {
+	setting := func(key string) string {
+		value, _ := config.Config[key].(string)
+		return value
+	}
+
+	switch config.Type {
+	case "git":
+		repository := setting("repository")
+		if repository == "" {
+			repository = "."
+		}
+		return sanitizeLocation(repository) + "@" + setting("ref")
+	case "http":
+		return sanitizeLocation(setting("base_url"))
+	case "s3":
+		return setting("bucket")
+	default:
+		return ""
+	}
+}
+
+// sanitizeLocation removes the user information, query and fragment of a URL.
+// Paths are returned as is; URLs that cannot be parsed are dropped rather than risking a leak.
+func sanitizeLocation(location string) string {
+	if !strings.Contains(location, "://") {
+		return location
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// backendFor returns the vars source of a vars_files entry, without its label: the
+// backend, its type and key, and the entry's path within it
+func (s *varsSources) backendFor(varsFile VarsFile) (varsSource, error) {
+	source, path := varsFile.Source, varsFile.Path
+	if source == nil {
+		var err error
+		if source, path, err = parseSourceURI(varsFile.Path); err != nil {
+			return varsSource{}, err
+		}
+	}
+	entry := varsFile
+	entry.Path = path
+	if source == nil {
+		return varsSource{VarsFile: entry, Type: s.defaultType, Backend: s.defaultBackend}, nil
+	}
+
+	// Local sources with the same path declared in different directories differ
+	key := source.Type + " " + backendID(source) + " " + source.dir
+	backendInstance, ok := s.backends[key]
+	if !ok {
+		var err error
+		if backendInstance, err = newBackend(source); err != nil {
+			return varsSource{}, err
+		}
+		backendInstance = s.config.withCache(backendInstance, source)
+		s.backends[key] = backendInstance
+	}
+	return varsSource{VarsFile: entry, Type: source.Type, Backend: backendInstance, backendKey: key}, nil
+}
+
+// expand expands glob patterns in vars_files entries in lexical order.
+// Matches inherit the optional flag of their pattern; a pattern matching nothing
+// is an error unless it is optional. A file read more than once from the same backend
+// is kept at its first position, optional only if every entry naming it is.
+func (s *varsSources) expand(ctx context.Context, varsFiles []VarsFile) ([]varsSource, error) {
+	expanded := make([]varsSource, 0, len(varsFiles))
+	seen := make(map[string]int)
+	add := func(source varsSource) {
+		if i, ok := seen[source.key()]; ok {
+			expanded[i].Optional = expanded[i].Optional && source.Optional
+			if expanded[i].SHA256 == "" {
+				expanded[i].SHA256 = source.SHA256
+			}
+			return
+		}
+		seen[source.key()] = len(expanded)
+		expanded = append(expanded, source)
+	}
+
+	for _, varsFile := range varsFiles {
+		source, err := s.backendFor(varsFile)
+		if err != nil {
+			return nil, fmt.Errorf("vars file %s: %w", varsFile.Path, err)
+		}
+		path, backendType := source.Path, source.Type
+
+		// Relative paths are rebased onto the main file's directory, which the local
+		// backend knows nothing about: read them by absolute path instead, relative to
+		// the backend's path if set, or else to the file that declared them
+		local := varsFile.baseDir != "" && backendType == "local"
+		var root, rebase string
+		if local {
+			root = varsFile.declDir
+			if s.localRoot != "" {
+				root = s.localRoot
+			}
+			declared, err := filepath.Rel(varsFile.declDir, filepath.Join(varsFile.baseDir, filepath.FromSlash(path)))
+			if err != nil {
+				return nil, fmt.Errorf("vars file %s: %w", varsFile.Path, err)
+			}
+			path = filepath.Join(root, declared)
+			if rebase, err = filepath.Rel(varsFile.baseDir, varsFile.declDir); err != nil {
+				return nil, fmt.Errorf("vars file %s: %w", varsFile.Path, err)
+			}
+		}
+
+		if !varsFile.IsPattern() {
+			source.Path = path
+			source.Label = varsFile.Path
+			add(source)
+			continue
+		}
+
+		matches, err := backend.Glob(ctx, source.Backend, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand vars file pattern %s: %w", varsFile.Path, err)
+		}
+		slog.Debug("Expanded vars file pattern", "pattern", varsFile.Path, "matches", matches)
+
+		if len(matches) == 0 && !varsFile.Optional {
+			return nil, fmt.Errorf("vars file pattern %s matched no files", varsFile.Path)
+		}
+
+		// Keep the URI prefix of the pattern in the labels of its matches
+		prefix := strings.TrimSuffix(varsFile.Path, path)
+		for _, match := range matches {
+			label := prefix + match
+			if local {
+				// Label absolute matches like the pattern they came from
+				if rel, err := filepath.Rel(root, match); err == nil {
+					label = rebasePath(rebase, filepath.ToSlash(rel))
+				}
+			}
+			add(varsSource{
+				VarsFile:   VarsFile{Path: match, Optional: varsFile.Optional, Source: varsFile.Source},
+				Label:      label,
+				Type:       backendType,
+				Backend:    source.Backend,
+				backendKey: source.backendKey,
+			})
+		}
+	}
+
+	return expanded, nil
+}
+
+// parseSourceURI splits a vars_files path with a URI scheme into the backend it is read from
+// and its path within that backend. Plain paths return a nil backend.
+//
+//	s3://BUCKET/KEY
+//	git+ref://REF/PATH        (REF must not contain "/")
+//	http(s)://HOST/PATH
+//	vault://MOUNT/PATH
+func parseSourceURI(uri string) (*Backend, string, error) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok {
+		return nil, uri, nil
+	}
+
+	host, path, _ := strings.Cut(rest, "/")
+	if host == "" || path == "" {
+		return nil, "", fmt.Errorf("invalid vars file source %s", uri)
+	}
+
+	switch scheme {
+	case "s3":
+		return &Backend{Type: "s3", Config: map[string]interface{}{"bucket": host}}, path, nil
+	case "git+ref":
+		return &Backend{Type: "git", Config: map[string]interface{}{"ref": host}}, path, nil
+	case "http", "https":
+		return &Backend{Type: "http", Config: map[string]interface{}{"base_url": scheme + "://" + host + "/"}}, path, nil
+	case "vault":
+		return &Backend{Type: "vault", Config: map[string]interface{}{"mount": host}}, path, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported vars file source scheme %q in %s (expected s3, git+ref, http, https or vault)", scheme, uri)
+	}
+}
+
+// isSourceURI reports whether a vars_files path names its own source
+func isSourceURI(path string) bool {
+	return strings.Contains(path, "://")
+}
+
+// fetchedFile describes the content a vars file was read from
+type fetchedFile struct {
+	// SHA256 is the checksum of the content, empty for variables produced directly
+	SHA256 string
+	// Version is the version or ETag the backend reported with the content, if any
+	Version string
+}
+
+// readVariables reads the variables of a vars file, directly from backends that
+// produce variables or by parsing the fetched tfvars file.
+// It also describes the fetched file; variables produced directly have no checksum.
+func readVariables(ctx context.Context, source varsSource) ([]tfvars.Variable, fetchedFile, error) {
+	var variables []tfvars.Variable
+	var fetched fetchedFile
+
+	if getter, ok := source.Backend.(backend.VariablesGetter); ok {
+		if source.SHA256 != "" {
+			return nil, fetchedFile{}, fmt.Errorf("sha256 is not supported by the configured backend")
+		}
+		var err error
+		if variables, err = getter.GetVariables(ctx, source.Path); err != nil {
+			return nil, fetchedFile{}, err
+		}
+	} else {
+		content, version, err := fetchVarsFile(ctx, source.Backend, source.VarsFile)
+		if err != nil {
+			return nil, fetchedFile{}, err
+		}
+		if variables, err = tfvars.ParseTfvars(content); err != nil {
+			return nil, fetchedFile{}, fmt.Errorf("invalid tfvars: %w", err)
+		}
+		sum := sha256.Sum256(content)
+		fetched = fetchedFile{SHA256: hex.EncodeToString(sum[:]), Version: version}
+	}
+
+	for i := range variables {
+		if variables[i].Source == "" {
+			variables[i].Source = source.Label
+		}
+	}
+	return variables, fetched, nil
+}
+
+// fetchVarsFile retrieves a vars file and its version from the backend and verifies
+// its pinned checksum
+func fetchVarsFile(ctx context.Context, backendInstance backend.Backend, varsFile VarsFile) ([]byte, string, error) {
+	content, version, err := backend.GetVersioned(ctx, backendInstance, varsFile.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if varsFile.SHA256 != "" {
+		sum := sha256.Sum256(content)
+		if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, varsFile.SHA256) {
+			return nil, "", fmt.Errorf("checksum mismatch: expected sha256 %s, got %s", varsFile.SHA256, actual)
+		}
+	}
+
+	return content, version, nil
+}
+
+// NewBackend creates the vars backend configured for a resolved environment.
+// Environments without a backend use the local backend.
+func NewBackend(env *Environment) (backend.Backend, error) {
+	if env.Backend == nil {
+		// Default to local backend if no backend is specified
+		backendInstance, err := local.New(backend.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create default local backend: %w", err)
+		}
+		return backendInstance, nil
+	}
+
+	return newBackend(env.Backend)
+}
+
+// newBackend creates a backend from its configuration
+func newBackend(config *Backend) (backend.Backend, error) {
+	switch config.Type {
+	case "local":
+		backendInstance, err := local.New(config.localConfig())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create local backend: %w", err)
+		}
+		return backendInstance, nil
+	case "git":
+		backendInstance, err := git.New(config.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create git backend: %w", err)
+		}
+		return backendInstance, nil
+	case "http":
+		backendInstance, err := httpbackend.New(config.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create http backend: %w", err)
+		}
+		return backendInstance, nil
+	case "vault":
+		backendInstance, err := vault.New(config.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create vault backend: %w", err)
+		}
+		return backendInstance, nil
+	case "s3":
+		return nil, fmt.Errorf("s3 backend not yet implemented")
+	default:
+		return nil, fmt.Errorf("unknown backend type: %s", config.Type)
+	}
+}
+
+// PublishVarsFile stores rendered vars in the vars backend of a resolved environment.
+// Publishing to a path the environment reads its own vars from is refused.
+func (c *Config) PublishVarsFile(ctx context.Context, env *Environment, path string, content []byte) error {
+	for _, varsFile := range env.VarsFiles {
+		if varsFile.Source == nil && !isSourceURI(varsFile.Path) && !varsFile.IsPattern() &&
+			filepath.Clean(varsFile.Path) == filepath.Clean(path) {
+			return fmt.Errorf("refusing to overwrite %s, a vars file of environment %s", path, env.Name)
+		}
+	}
+
+	if c.offline && env.Backend != nil && env.Backend.Type != "local" {
+		return fmt.Errorf("cannot publish to the %s backend in offline mode", env.Backend.Type)
+	}
+
+	backendInstance, err := NewBackend(env)
+	if err != nil {
+		return err
+	}
+	putter, ok := backendInstance.(backend.Putter)
+	if !ok {
+		return fmt.Errorf("%s backend does not support publishing", env.BackendType())
+	}
+
+	if err := putter.Put(ctx, path, content); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", path, err)
+	}
+	return nil
+}
+
+// BackendType returns the vars backend type of a resolved environment, local by default
+func (e *Environment) BackendType() string {
+	if e.Backend == nil {
+		return "local"
+	}
+	return e.Backend.Type
+}
+
+// localConfig returns the configuration of a local backend with a relative path
+// resolved against the directory of the file that declared it
+func (b *Backend) localConfig() backend.Config {
+	if b.dir == "" {
+		return b.Config
+	}
+	path, _ := b.Config["path"].(string)
+	if filepath.IsAbs(path) {
+		return b.Config
+	}
+
+	config := make(backend.Config, len(b.Config)+1)
+	for key, value := range b.Config {
+		config[key] = value
+	}
+	config["path"] = filepath.Join(b.dir, path)
+	return config
+}
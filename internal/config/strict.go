@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// checkUnknownFields reports the first key in node that has no matching field in t.
+// Errors carry the line and column of the offending key.
+func checkUnknownFields(node *yaml.Node, t reflect.Type, path string) error {
+	if node == nil {
+		return nil
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := checkUnknownFields(child, t, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	case yaml.AliasNode:
+		return checkUnknownFields(node.Alias, t, path)
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		// Scalar forms are handled by custom unmarshalers such as VarsFile
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		fields := make(map[string]reflect.Type)
+		for _, field := range yamlFields(t) {
+			fields[field.Name] = field.Type
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				return fmt.Errorf("line %d, column %d: unknown field %q in %s (expected one of: %s)",
+					key.Line, key.Column, key.Value, describePath(path), strings.Join(fieldNames(fields), ", "))
+			}
+			if err := checkUnknownFields(value, fieldType, joinPath(path, key.Value)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, item := range node.Content {
+			if err := checkUnknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := checkUnknownFields(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fieldNames returns the sorted names of fields
+func fieldNames(fields map[string]reflect.Type) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// joinPath appends a key to a dotted path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// describePath returns a readable name for a path, using "top level" for the root
+func describePath(path string) string {
+	if path == "" {
+		return "top level"
+	}
+	return path
+}
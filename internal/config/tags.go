@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tagKeyPattern matches valid tag keys and values
+var tagKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.-]*[A-Za-z0-9])?$`)
+
+// parseTag splits a tag of the form "key" or "key=value"
+func parseTag(tag string) (string, string) {
+	key, value, _ := strings.Cut(tag, "=")
+	return key, value
+}
+
+// TagMap returns tags as a map from key to value. Bare tags have an empty value.
+func TagMap(tags []string) map[string]string {
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		key, value := parseTag(tag)
+		result[key] = value
+	}
+	return result
+}
+
+// mergeTags overlays child tags onto base. A child tag replaces the base tag with the same key.
+func mergeTags(base, child []string) []string {
+	if len(base) == 0 {
+		return child
+	}
+	if len(child) == 0 {
+		return base
+	}
+
+	merged := make([]string, 0, len(base)+len(child))
+	index := make(map[string]int, len(base)+len(child))
+	for _, tag := range append(append([]string{}, base...), child...) {
+		key, _ := parseTag(tag)
+		if i, ok := index[key]; ok {
+			merged[i] = tag
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, tag)
+	}
+	return merged
+}
+
+// validateTags checks that every tag is a valid "key" or "key=value" and that keys are unique
+func validateTags(tags []string) error {
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		key, value := parseTag(tag)
+		if !tagKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid tag %q: key must be alphanumeric and may contain '-', '_' and '.'", tag)
+		}
+		if strings.Contains(tag, "=") && !tagKeyPattern.MatchString(value) {
+			return fmt.Errorf("invalid tag %q: value must be alphanumeric and may contain '-', '_' and '.'", tag)
+		}
+		if seen[key] {
+			return fmt.Errorf("duplicate tag %q", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
@@ -1,34 +1,136 @@
 package config
 
+import (
+	"time"
+
+	"github.com/marcy326/tivor/internal/backend/cache"
+)
+
 // Config represents the overall structure of tivor.yaml
 type Config struct {
-	Version      string        `yaml:"version"`
+	Version string `yaml:"version"`
+	// Include lists additional files or glob patterns, relative to this file,
+	// whose defaults and environments are merged into this configuration
+	Include      []string      `yaml:"include,omitempty"`
 	Defaults     *Defaults     `yaml:"defaults,omitempty"`
 	Secrets      *Secrets      `yaml:"secrets,omitempty"`
 	Environments []Environment `yaml:"environments"`
+
+	// cache stores files fetched from remote vars backends, if set
+	cache *cache.Cache
+	// offline serves remote vars files only from the cache
+	offline bool
+	// path is the main configuration file
+	path string
 }
 
 // Defaults represents common default settings across environments
 type Defaults struct {
-	VarsFiles []string `yaml:"vars_files,omitempty"`
+	VarsFiles []VarsFile             `yaml:"vars_files,omitempty"`
+	Vars      map[string]interface{} `yaml:"vars,omitempty"`
+	Sensitive []string               `yaml:"sensitive,omitempty"`
+	Execution *Execution             `yaml:"execution,omitempty"`
+	Hooks     *Hooks                 `yaml:"hooks,omitempty"`
+
+	// varSources names the included file that declared each default variable;
+	// variables of the main file are not listed
+	varSources map[string]string
 }
 
 // Secrets represents secret management configuration
 type Secrets struct {
-	Engine         string `yaml:"engine"`
+	Engine         string `yaml:"engine" schema:"required"`
 	SopsConfigPath string `yaml:"sops_config_path,omitempty"`
 }
 
 // Environment represents configuration for individual environments
 type Environment struct {
-	Name      string   `yaml:"name"`
-	Inherits  string   `yaml:"inherits,omitempty"`
-	VarsFiles []string `yaml:"vars_files,omitempty"`
-	Backend   *Backend `yaml:"backend,omitempty"`
+	Name     string `yaml:"name" schema:"required"`
+	Inherits string `yaml:"inherits,omitempty"`
+	// Tags label the environment for selectors, as "key" or "key=value".
+	// They are inherited; a child tag replaces the parent tag with the same key.
+	Tags      []string   `yaml:"tags,omitempty"`
+	VarsFiles []VarsFile `yaml:"vars_files,omitempty"`
+	// Vars are inline variables applied after all vars files
+	Vars map[string]interface{} `yaml:"vars,omitempty"`
+	// Sensitive names variables whose values are redacted from logs and output.
+	// They add to the inherited names.
+	Sensitive []string `yaml:"sensitive,omitempty"`
+	// Backend is the vars backend the vars files are read from
+	// (not the Terraform state backend; "backend" before version 1.1)
+	Backend *Backend `yaml:"vars_backend,omitempty"`
+	// PlanArgs and ApplyArgs are extra arguments passed to terraform plan/apply.
+	// They are inherited from the parent when not set.
+	PlanArgs  []string   `yaml:"plan_args,omitempty"`
+	ApplyArgs []string   `yaml:"apply_args,omitempty"`
+	Execution *Execution `yaml:"execution,omitempty"`
+	Hooks     *Hooks     `yaml:"hooks,omitempty"`
+	// WorkingDir is the Terraform working directory used when --working-dir is not given
+	WorkingDir string `yaml:"working_dir,omitempty"`
+	// Workspace is the Terraform workspace selected before plan/apply.
+	// It is not inherited, so a child never targets its parent's state.
+	Workspace string `yaml:"workspace,omitempty"`
+	// Protected marks environments to change with care, as shown by tivor env list.
+	// It is not inherited, like the workspace.
+	Protected bool `yaml:"protected,omitempty"`
+
+	// Matrix expands this environment into one environment per combination of axis values.
+	// It is expanded while loading, so loaded environments never have a matrix.
+	Matrix *Matrix `yaml:"matrix,omitempty"`
+
+	// Source is the included file that declared the environment.
+	// It is empty for environments declared in the main configuration file.
+	Source string `yaml:"-"`
 }
 
 // Backend represents storage backend configuration
 type Backend struct {
-	Type   string                 `yaml:"type"`
+	Type   string                 `yaml:"type" schema:"required"`
 	Config map[string]interface{} `yaml:"config,omitempty"`
+
+	// dir is the absolute directory of the file that declared the backend;
+	// a relative local path is resolved against it
+	dir string
+}
+
+// Execution represents how Terraform commands are run
+type Execution struct {
+	Timeouts *Timeouts `yaml:"timeouts,omitempty"`
+	Retry    *Retry    `yaml:"retry,omitempty"`
+	// VarsInjection is how variables are passed to Terraform: "file" (default),
+	// "env" for TF_VAR_ environment variables or "pipe" for an inherited pipe
+	VarsInjection string `yaml:"vars_injection,omitempty"`
+}
+
+// Timeouts represents the timeout of each attempt of a Terraform command
+type Timeouts struct {
+	Init  time.Duration `yaml:"init,omitempty"`
+	Plan  time.Duration `yaml:"plan,omitempty"`
+	Apply time.Duration `yaml:"apply,omitempty"`
+}
+
+// Retry represents the retry policy for transient Terraform failures
+type Retry struct {
+	MaxAttempts    int           `yaml:"max_attempts,omitempty"`
+	InitialBackoff time.Duration `yaml:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration `yaml:"max_backoff,omitempty"`
+	Patterns       []string      `yaml:"patterns,omitempty"`
+}
+
+// Hooks represents commands run around each Terraform step
+type Hooks struct {
+	PreInit   []Hook `yaml:"pre_init,omitempty"`
+	PrePlan   []Hook `yaml:"pre_plan,omitempty"`
+	PostPlan  []Hook `yaml:"post_plan,omitempty"`
+	PreApply  []Hook `yaml:"pre_apply,omitempty"`
+	PostApply []Hook `yaml:"post_apply,omitempty"`
+	OnFailure []Hook `yaml:"on_failure,omitempty"`
+}
+
+// Hook represents a single shell command run by a lifecycle hook
+type Hook struct {
+	Name    string `yaml:"name,omitempty"`
+	Command string `yaml:"command" schema:"required"`
+	// OnError is "fail" (default) to abort the run or "continue" to only log the failure
+	OnError string `yaml:"on_error,omitempty"`
 }
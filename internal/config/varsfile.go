@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VarsFile represents an entry of vars_files.
+// It can be written as a plain path, where a trailing "?" marks the file as
+// optional, or as a mapping with path, optional, sha256 and source keys.
+// A path with a URI scheme such as s3://bucket/key names its own source.
+type VarsFile struct {
+	Path     string `yaml:"path" schema:"required"`
+	Optional bool   `yaml:"optional,omitempty"`
+	// SHA256 pins the content of the file to a hex-encoded SHA-256 checksum
+	SHA256 string `yaml:"sha256,omitempty"`
+	// Source reads this file from another backend than the environment's vars backend
+	Source *Backend `yaml:"source,omitempty"`
+
+	// baseDir is the absolute directory a relative path is relative to after rebasing,
+	// the main file's, and declDir that of the file that declared it. The local backend
+	// reads the file relative to declDir, or to its own path if one is set.
+	baseDir string
+	declDir string
+}
+
+// UnmarshalYAML accepts both the scalar and the mapping form.
+func (v *VarsFile) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*v = ParseVarsFile(node.Value)
+		return nil
+	}
+
+	type plain VarsFile
+	var decoded plain
+	if err := node.Decode(&decoded); err != nil {
+		return err
+	}
+	if decoded.Path == "" {
+		return fmt.Errorf("line %d: vars_files entry requires a path", node.Line)
+	}
+	*v = VarsFile(decoded)
+	return nil
+}
+
+// MarshalYAML writes the entry in its shortest form.
+func (v VarsFile) MarshalYAML() (interface{}, error) {
+	if v.SHA256 != "" || v.Source != nil {
+		type plain VarsFile
+		return plain(v), nil
+	}
+	return v.String(), nil
+}
+
+// String returns the entry in its scalar form.
+func (v VarsFile) String() string {
+	if v.Optional {
+		return v.Path + "?"
+	}
+	return v.Path
+}
+
+// IsPattern reports whether the path contains glob metacharacters.
+func (v VarsFile) IsPattern() bool {
+	return strings.ContainsAny(v.Path, "*?[")
+}
+
+// ParseVarsFile parses the scalar form of a vars_files entry.
+func ParseVarsFile(s string) VarsFile {
+	if strings.HasSuffix(s, "?") {
+		return VarsFile{Path: strings.TrimSuffix(s, "?"), Optional: true}
+	}
+	return VarsFile{Path: s}
+}
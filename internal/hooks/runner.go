@@ -0,0 +1,113 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/marcy326/tivor/internal/config"
+	"github.com/marcy326/tivor/internal/redact"
+)
+
+// Environment variables exposed to hook commands
+const (
+	EnvEnvironment = "TIVOR_ENVIRONMENT"
+	EnvHook        = "TIVOR_HOOK"
+	EnvVarsFile    = "TIVOR_VARS_FILE"
+	EnvWorkingDir  = "TIVOR_WORKING_DIR"
+	EnvPlanJSON    = "TIVOR_PLAN_JSON"
+	EnvFailedStep  = "TIVOR_FAILED_STEP"
+	EnvError       = "TIVOR_ERROR"
+)
+
+// waitDelay is how long the output of a cancelled hook is awaited, in case processes
+// it started keep it open
+const waitDelay = 5 * time.Second
+
+// Runner executes the lifecycle hooks of a resolved environment
+type Runner struct {
+	hooks *config.Hooks
+	env   map[string]string
+}
+
+// NewRunner creates a Runner that exposes env to every hook in addition to the process environment
+func NewRunner(hooks *config.Hooks, env map[string]string) *Runner {
+	vars := make(map[string]string, len(env))
+	for k, v := range env {
+		vars[k] = v
+	}
+	return &Runner{
+		hooks: hooks,
+		env:   vars,
+	}
+}
+
+// Set exposes an additional environment variable to subsequent hooks
+func (r *Runner) Set(key, value string) {
+	r.env[key] = value
+}
+
+// Run executes the hooks registered for event in order.
+// A failing hook aborts the run unless its on_error is "continue".
+func (r *Runner) Run(ctx context.Context, event string) error {
+	for i, hook := range r.hooks.For(event) {
+		name := hook.Name
+		if name == "" {
+			name = fmt.Sprintf("%s[%d]", event, i)
+		}
+
+		slog.Info("Running hook", "hook", name, "event", event)
+		if err := r.runHook(ctx, event, hook); err != nil {
+			if hook.OnError == config.HookOnErrorContinue {
+				slog.Warn("Hook failed, continuing", "hook", name, "error", err)
+				continue
+			}
+			return fmt.Errorf("hook %s failed: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// runHook executes a single hook command through the system shell
+func (r *Runner) runHook(ctx context.Context, event string, hook config.Hook) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", hook.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	}
+
+	// Capture output so known secret values can be scrubbed, as for Terraform
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = waitDelay
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", EnvHook, event))
+
+	// Sort for a deterministic environment
+	keys := make([]string, 0, len(r.env))
+	for k := range r.env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, r.env[k]))
+	}
+
+	err := cmd.Run()
+
+	if stdout.Len() > 0 {
+		fmt.Print(redact.String(stdout.String()))
+	}
+	if stderr.Len() > 0 {
+		fmt.Fprint(os.Stderr, redact.String(stderr.String()))
+	}
+
+	return err
+}
@@ -0,0 +1,156 @@
+package redact
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces secret values in output
+const Placeholder = "(sensitive)"
+
+// MinLength is the length below which values are not scrubbed from free text,
+// as they would match unrelated output
+const MinLength = 4
+
+// Redactor replaces known secret values in text
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  map[string]bool
+	replacer *strings.Replacer
+}
+
+// New creates a redactor without secrets
+func New() *Redactor {
+	return &Redactor{secrets: make(map[string]bool)}
+}
+
+// defaultRedactor collects the secrets of the running command
+var defaultRedactor = New()
+
+// Default returns the redactor used for logs and Terraform output
+func Default() *Redactor {
+	return defaultRedactor
+}
+
+// Add registers secret values with the default redactor
+func Add(values ...string) {
+	defaultRedactor.Add(values...)
+}
+
+// String redacts s with the default redactor
+func String(s string) string {
+	return defaultRedactor.String(s)
+}
+
+// Add registers secret values. Values shorter than MinLength are ignored.
+func (r *Redactor) Add(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := false
+	for _, value := range values {
+		if len(value) >= MinLength && !r.secrets[value] {
+			r.secrets[value] = true
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+
+	// Replace longer secrets first, so a secret containing another is replaced whole
+	secrets := make([]string, 0, len(r.secrets))
+	for secret := range r.secrets {
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		if len(secrets[i]) != len(secrets[j]) {
+			return len(secrets[i]) > len(secrets[j])
+		}
+		return secrets[i] < secrets[j]
+	})
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, secret := range secrets {
+		pairs = append(pairs, secret, Placeholder)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// String replaces every registered secret in s with the placeholder
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	replacer := r.replacer
+	r.mu.RUnlock()
+
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// handler redacts the message and attributes of log records
+type handler struct {
+	inner    slog.Handler
+	redactor *Redactor
+}
+
+// NewHandler returns a log handler redacting the secrets known to r before passing
+// records to inner
+func NewHandler(inner slog.Handler, r *Redactor) slog.Handler {
+	return &handler{inner: inner, redactor: r}
+}
+
+// Enabled reports whether the inner handler handles records at the level
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle redacts the record and passes it to the inner handler
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.String(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.attr(attr))
+		return true
+	})
+	return h.inner.Handle(ctx, redacted)
+}
+
+// WithAttrs redacts the attributes and passes them to the inner handler
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.attr(attr)
+	}
+	return &handler{inner: h.inner.WithAttrs(redacted), redactor: h.redactor}
+}
+
+// WithGroup passes the group to the inner handler
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{inner: h.inner.WithGroup(name), redactor: h.redactor}
+}
+
+// attr redacts the value of an attribute, formatting non-string values only if they contain a secret
+func (h *handler) attr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, h.redactor.String(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, len(group))
+		for i, member := range group {
+			redacted[i] = h.attr(member)
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		formatted := fmt.Sprint(value.Any())
+		if redacted := h.redactor.String(formatted); redacted != formatted {
+			return slog.String(attr.Key, redacted)
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
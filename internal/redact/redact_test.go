@@ -0,0 +1,54 @@
+package redact
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactorString(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		input   string
+		want    string
+	}{
+		{name: "no secrets", input: "password=hunter22", want: "password=hunter22"},
+		{name: "single secret", secrets: []string{"hunter22"}, input: "password=hunter22", want: "password=(sensitive)"},
+		{name: "every occurrence", secrets: []string{"hunter22"}, input: "hunter22 hunter22", want: "(sensitive) (sensitive)"},
+		{name: "short values are ignored", secrets: []string{"abc"}, input: "abc", want: "abc"},
+		{name: "longest secret first", secrets: []string{"token", "token-extended"}, input: "token-extended", want: "(sensitive)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New()
+			r.Add(tt.secrets...)
+			if got := r.String(tt.input); got != tt.want {
+				t.Errorf("String(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := New()
+	r.Add("hunter22")
+
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewTextHandler(&buf, nil), r)).With("preset", "hunter22")
+	logger.Info("value is hunter22",
+		"value", "hunter22",
+		"list", []string{"a", "hunter22"},
+		slog.Group("group", "nested", "hunter22"),
+		"count", 3)
+
+	output := buf.String()
+	if strings.Contains(output, "hunter22") {
+		t.Errorf("log output contains the secret: %s", output)
+	}
+	if !strings.Contains(output, "count=3") {
+		t.Errorf("log output lost a non-secret attribute: %s", output)
+	}
+}
@@ -0,0 +1,63 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// allowedArgs lists the extra arguments accepted for each command.
+// Arguments controlling variables (-var, -var-file) are deliberately excluded
+// so the vars file generated by tivor cannot be overridden by accident.
+var allowedArgs = map[string]map[string]bool{
+	"plan": {
+		"target":           true,
+		"replace":          true,
+		"parallelism":      true,
+		"lock":             true,
+		"lock-timeout":     true,
+		"refresh":          true,
+		"refresh-only":     true,
+		"compact-warnings": true,
+		"no-color":         true,
+	},
+	"apply": {
+		"target":           true,
+		"replace":          true,
+		"parallelism":      true,
+		"lock":             true,
+		"lock-timeout":     true,
+		"refresh":          true,
+		"refresh-only":     true,
+		"compact-warnings": true,
+		"no-color":         true,
+	},
+}
+
+// ValidateArgs checks that every extra argument is allowed for the given command.
+// Arguments must be single tokens in the form -name or -name=value.
+func ValidateArgs(command string, args []string) error {
+	allowed, ok := allowedArgs[command]
+	if !ok {
+		if len(args) > 0 {
+			return fmt.Errorf("extra arguments are not supported for terraform %s", command)
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("invalid argument %q: must be in the form -name or -name=value", arg)
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if i := strings.Index(name, "="); i >= 0 {
+			name = name[:i]
+		}
+
+		if !allowed[name] {
+			return fmt.Errorf("argument %q is not allowed for terraform %s", arg, command)
+		}
+	}
+
+	return nil
+}
@@ -9,38 +9,174 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/marcy326/tivor/internal/redact"
+	"github.com/marcy326/tivor/internal/tfvars"
 )
 
+// DefaultGracePeriod is how long Terraform may take to shut down after an interrupt
+const DefaultGracePeriod = 30 * time.Second
+
 // Executor handles Terraform command execution
 type Executor struct {
-	workingDir string
-	varsFile   string
+	workingDir  string
+	varsFile    string
+	workspace   string
+	gracePeriod time.Duration
+	timeouts    map[string]time.Duration
+	retry       *RetryPolicy
+	attempts    []Attempt
+	planFile    string
+	// varsEnv holds TF_VAR_ entries passed instead of a vars file
+	varsEnv []string
+	// varsPipe holds vars file content passed through an inherited pipe instead of a file
+	varsPipe []byte
 }
 
 // NewExecutor creates a new Terraform executor
 func NewExecutor(workingDir, varsFile string) *Executor {
 	return &Executor{
-		workingDir: workingDir,
-		varsFile:   varsFile,
+		workingDir:  workingDir,
+		varsFile:    varsFile,
+		gracePeriod: DefaultGracePeriod,
+	}
+}
+
+// SetGracePeriod sets how long Terraform may take to exit after being interrupted
+// before it is killed
+func (e *Executor) SetGracePeriod(gracePeriod time.Duration) {
+	e.gracePeriod = gracePeriod
+}
+
+// SetTimeout sets the timeout of each attempt of a command (e.g. "init", "plan", "apply").
+// A zero duration disables the timeout.
+func (e *Executor) SetTimeout(command string, timeout time.Duration) {
+	if e.timeouts == nil {
+		e.timeouts = make(map[string]time.Duration)
 	}
+	e.timeouts[command] = timeout
+}
+
+// SetRetryPolicy sets the policy used to retry failed commands
+func (e *Executor) SetRetryPolicy(policy *RetryPolicy) {
+	e.retry = policy
+}
+
+// Attempts returns every command attempt made by the executor so far
+func (e *Executor) Attempts() []Attempt {
+	return e.attempts
+}
+
+// SetPlanFile makes Plan save the plan to the given path so it can be inspected with ShowPlanJSON
+func (e *Executor) SetPlanFile(planFile string) {
+	e.planFile = planFile
 }
 
-// Plan executes terraform plan with the configured variables
-func (e *Executor) Plan(ctx context.Context) error {
-	return e.executeCommand(ctx, "plan", []string{})
+// SetEnvVariables passes variables to plan, apply and init as TF_VAR_<name> environment
+// variables instead of a vars file. Note that Terraform gives environment variables a lower
+// precedence than terraform.tfvars and *.auto.tfvars files in the working directory.
+func (e *Executor) SetEnvVariables(variables []tfvars.Variable) {
+	e.varsEnv = EnvVariables(variables)
+	e.varsFile = ""
 }
 
-// Apply executes terraform apply with the configured variables
-func (e *Executor) Apply(ctx context.Context) error {
-	return e.executeCommand(ctx, "apply", []string{"-auto-approve"})
+// SetVarsPipe passes vars file content to plan, apply and init through a pipe inherited
+// by Terraform, so it is never written to disk
+func (e *Executor) SetVarsPipe(content []byte) {
+	e.varsPipe = content
+	e.varsFile = ""
 }
 
-// executeCommand executes a terraform command with common options
+// SetWorkspace sets the Terraform workspace used by SelectWorkspace and VerifyWorkspace
+func (e *Executor) SetWorkspace(workspace string) {
+	e.workspace = workspace
+}
+
+// Plan executes terraform plan with the configured variables and extra arguments
+func (e *Executor) Plan(ctx context.Context, args ...string) error {
+	if err := ValidateArgs("plan", args); err != nil {
+		return err
+	}
+	if e.planFile != "" {
+		args = append(args, fmt.Sprintf("-out=%s", e.planFile))
+	}
+	return e.executeCommand(ctx, "plan", args)
+}
+
+// ShowPlanJSON returns the JSON representation of the plan saved by Plan
+func (e *Executor) ShowPlanJSON(ctx context.Context) ([]byte, error) {
+	if e.planFile == "" {
+		return nil, fmt.Errorf("no plan file configured")
+	}
+
+	output, err := e.runOutput(ctx, "show", "-json", e.planFile)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(output), nil
+}
+
+// Apply executes terraform apply with the configured variables and extra arguments
+func (e *Executor) Apply(ctx context.Context, args ...string) error {
+	if err := ValidateArgs("apply", args); err != nil {
+		return err
+	}
+	return e.executeCommand(ctx, "apply", append([]string{"-auto-approve"}, args...))
+}
+
+// executeCommand executes a terraform command with common options,
+// retrying it according to the retry policy
 func (e *Executor) executeCommand(ctx context.Context, command string, extraArgs []string) error {
+	maxAttempts := e.retry.attempts()
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		stderr, err := e.executeOnce(ctx, command, extraArgs)
+		e.attempts = append(e.attempts, Attempt{
+			Command:  command,
+			Number:   attempt,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+
+		if err == nil {
+			slog.Info("Terraform command completed successfully", "command", command, "attempt", attempt)
+			return nil
+		}
+
+		// Never retry after an interrupt or once the attempts are exhausted
+		if ctx.Err() != nil || attempt >= maxAttempts {
+			return err
+		}
+
+		pattern := e.retry.match(stderr)
+		if pattern == nil {
+			return err
+		}
+
+		backoff := e.retry.backoff(attempt)
+		slog.Warn("Terraform command failed with retryable error",
+			"command", command,
+			"attempt", attempt,
+			"max_attempts", maxAttempts,
+			"pattern", pattern.String(),
+			"backoff", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("terraform %s interrupted: %w", command, ctx.Err())
+		}
+	}
+}
+
+// executeOnce executes a terraform command once and returns its stderr
+func (e *Executor) executeOnce(ctx context.Context, command string, extraArgs []string) (string, error) {
 	// Check if terraform binary exists
-	terraformPath, err := exec.LookPath("terraform")
+	terraformPath, err := lookupTerraform()
 	if err != nil {
-		return fmt.Errorf("terraform binary not found in PATH: %w", err)
+		return "", err
 	}
 
 	// Build command arguments
@@ -50,16 +186,36 @@ func (e *Executor) executeCommand(ctx context.Context, command string, extraArgs
 	if e.varsFile != "" {
 		args = append(args, fmt.Sprintf("-var-file=%s", e.varsFile))
 	}
+	var varsPipe *os.File
+	if e.varsPipe != nil {
+		reader, pipePath, cleanup, err := newVarsPipe(e.varsPipe)
+		if err != nil {
+			return "", err
+		}
+		defer cleanup()
+		varsPipe = reader
+		args = append(args, fmt.Sprintf("-var-file=%s", pipePath))
+	}
 
 	// Add extra arguments
 	args = append(args, extraArgs...)
 
-	// Create command
-	cmd := exec.CommandContext(ctx, terraformPath, args...)
+	// Apply the per-command timeout
+	cmdCtx := ctx
+	timeout := e.timeouts[command]
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		cmdCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
-	// Set working directory
-	if e.workingDir != "" {
-		cmd.Dir = e.workingDir
+	// Create command
+	cmd := e.newCommand(cmdCtx, terraformPath, args)
+	if e.varsEnv != nil {
+		cmd.Env = append(os.Environ(), e.varsEnv...)
+	}
+	if varsPipe != nil {
+		cmd.ExtraFiles = []*os.File{varsPipe}
 	}
 
 	// Set up output capture
@@ -75,21 +231,26 @@ func (e *Executor) executeCommand(ctx context.Context, command string, extraArgs
 	// Execute command
 	err = cmd.Run()
 
-	// Print outputs
+	// Print outputs, scrubbing known secret values
 	if stdout.Len() > 0 {
-		fmt.Print(stdout.String())
+		fmt.Print(redact.String(stdout.String()))
 	}
 
 	if stderr.Len() > 0 {
-		fmt.Fprint(os.Stderr, stderr.String())
+		fmt.Fprint(os.Stderr, redact.String(stderr.String()))
 	}
 
 	if err != nil {
-		return fmt.Errorf("terraform %s failed: %w", command, err)
+		if ctx.Err() != nil {
+			return stderr.String(), fmt.Errorf("terraform %s interrupted: %w", command, ctx.Err())
+		}
+		if cmdCtx.Err() != nil {
+			return stderr.String(), fmt.Errorf("terraform %s timed out after %s: %w", command, timeout, cmdCtx.Err())
+		}
+		return stderr.String(), fmt.Errorf("terraform %s failed: %w", command, err)
 	}
 
-	slog.Info("Terraform command completed successfully", "command", command)
-	return nil
+	return stderr.String(), nil
 }
 
 // ValidateWorkingDirectory checks if the working directory contains Terraform files
@@ -122,3 +283,114 @@ func (e *Executor) ValidateWorkingDirectory() error {
 func (e *Executor) Init(ctx context.Context) error {
 	return e.executeCommand(ctx, "init", []string{})
 }
+
+// Validate runs terraform validate after initializing the working directory
+// without a state backend, so no credentials or state access are needed
+func (e *Executor) Validate(ctx context.Context) error {
+	if _, err := e.runOutput(ctx, "init", "-backend=false", "-input=false", "-no-color"); err != nil {
+		return err
+	}
+	if _, err := e.runOutput(ctx, "validate", "-no-color"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SelectWorkspace selects the configured workspace, creating it if it does not exist.
+// It is a no-op when no workspace is configured.
+func (e *Executor) SelectWorkspace(ctx context.Context) error {
+	if e.workspace == "" {
+		return nil
+	}
+
+	slog.Info("Selecting Terraform workspace", "workspace", e.workspace)
+	if _, err := e.runOutput(ctx, "workspace", "select", "-or-create", e.workspace); err != nil {
+		return fmt.Errorf("failed to select workspace %s: %w", e.workspace, err)
+	}
+
+	return nil
+}
+
+// VerifyWorkspace checks that the active workspace matches the configured one.
+// It is a no-op when no workspace is configured.
+func (e *Executor) VerifyWorkspace(ctx context.Context) error {
+	if e.workspace == "" {
+		return nil
+	}
+
+	output, err := e.runOutput(ctx, "workspace", "show")
+	if err != nil {
+		return fmt.Errorf("failed to determine active workspace: %w", err)
+	}
+
+	active := strings.TrimSpace(output)
+	if active != e.workspace {
+		return fmt.Errorf("active workspace %q does not match expected workspace %q", active, e.workspace)
+	}
+
+	slog.Info("Terraform workspace verified", "workspace", active)
+	return nil
+}
+
+// runOutput executes a terraform command without the vars file and returns its stdout
+func (e *Executor) runOutput(ctx context.Context, args ...string) (string, error) {
+	terraformPath, err := lookupTerraform()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := e.newCommand(ctx, terraformPath, args)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	slog.Debug("Executing Terraform command",
+		"command", strings.Join(append([]string{"terraform"}, args...), " "),
+		"working_dir", e.workingDir)
+
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("terraform %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("terraform %s failed: %w", args[0], err)
+	}
+
+	return stdout.String(), nil
+}
+
+// newCommand creates a terraform command that is interrupted rather than killed
+// when ctx is cancelled, so Terraform can release state locks before exiting.
+// If it is still running after the grace period, it is killed.
+func (e *Executor) newCommand(ctx context.Context, terraformPath string, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, terraformPath, args...)
+
+	// Set working directory
+	if e.workingDir != "" {
+		cmd.Dir = e.workingDir
+	}
+
+	// Terraform runs in its own process group so that a terminal Ctrl-C reaches it
+	// only once, through tivor; a second interrupt would make Terraform exit immediately.
+	setProcessGroup(cmd)
+
+	cmd.Cancel = func() error {
+		slog.Warn("Interrupting Terraform", "command", args[0], "grace_period", e.gracePeriod)
+		if err := interruptProcess(cmd.Process); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = e.gracePeriod
+
+	return cmd
+}
+
+// lookupTerraform returns the path of the terraform binary
+func lookupTerraform() (string, error) {
+	terraformPath, err := exec.LookPath("terraform")
+	if err != nil {
+		return "", fmt.Errorf("terraform binary not found in PATH: %w", err)
+	}
+	return terraformPath, nil
+}
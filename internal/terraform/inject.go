@@ -0,0 +1,49 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+// Ways of passing variables to Terraform
+const (
+	// VarsInjectionFile passes a vars file written to a private temporary directory
+	VarsInjectionFile = "file"
+	// VarsInjectionEnv passes TF_VAR_<name> environment variables
+	VarsInjectionEnv = "env"
+	// VarsInjectionPipe passes a vars file through an inherited pipe, so no content is written to disk
+	VarsInjectionPipe = "pipe"
+)
+
+// ValidateVarsInjection checks that mode is a known way of passing variables
+func ValidateVarsInjection(mode string) error {
+	switch mode {
+	case "", VarsInjectionFile, VarsInjectionEnv, VarsInjectionPipe:
+		return nil
+	default:
+		return fmt.Errorf("unknown vars injection %q (expected %s, %s or %s)", mode, VarsInjectionFile, VarsInjectionEnv, VarsInjectionPipe)
+	}
+}
+
+// EnvVariables converts variables to TF_VAR_<name>=<value> environment entries.
+// Strings are passed unquoted; numbers, booleans and complex values keep their HCL
+// encoding, which Terraform parses according to the declared variable type.
+func EnvVariables(variables []tfvars.Variable) []string {
+	env := make([]string, 0, len(variables))
+	for _, variable := range variables {
+		env = append(env, fmt.Sprintf("TF_VAR_%s=%s", variable.Name, envValue(variable)))
+	}
+	return env
+}
+
+// envValue returns the value of a variable as Terraform expects it in TF_VAR_<name>
+func envValue(variable tfvars.Variable) string {
+	if variable.Type != tfvars.StringType {
+		return variable.Value
+	}
+	if unquoted, ok := tfvars.UnquoteString(variable.Value); ok {
+		return unquoted
+	}
+	return variable.Value
+}
@@ -0,0 +1,70 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/marcy326/tivor/internal/tfvars"
+)
+
+func TestEnvVariables(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable tfvars.Variable
+		want     string
+	}{
+		{
+			name:     "string",
+			variable: tfvars.Variable{Name: "region", Value: `"eu-west-1"`, Type: tfvars.StringType},
+			want:     "TF_VAR_region=eu-west-1",
+		},
+		{
+			name:     "template escapes",
+			variable: tfvars.Variable{Name: "name", Value: `"a-$${x}-%%{y}"`, Type: tfvars.StringType},
+			want:     "TF_VAR_name=a-${x}-%{y}",
+		},
+		{
+			name:     "backslash escapes",
+			variable: tfvars.Variable{Name: "motd", Value: `"say \"hi\"\n"`, Type: tfvars.StringType},
+			want:     "TF_VAR_motd=say \"hi\"\n",
+		},
+		{
+			name:     "number",
+			variable: tfvars.Variable{Name: "count", Value: "3", Type: tfvars.NumberType},
+			want:     "TF_VAR_count=3",
+		},
+		{
+			name:     "object keeps its HCL encoding",
+			variable: tfvars.Variable{Name: "tags", Value: "{\n  a = \"$${b}\"\n}", Type: tfvars.ObjectType},
+			want:     "TF_VAR_tags={\n  a = \"$${b}\"\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EnvVariables([]tfvars.Variable{tt.variable})
+			if want := []string{tt.want}; !reflect.DeepEqual(got, want) {
+				t.Errorf("EnvVariables() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateVarsInjection(t *testing.T) {
+	tests := []struct {
+		mode    string
+		wantErr bool
+	}{
+		{mode: ""},
+		{mode: VarsInjectionFile},
+		{mode: VarsInjectionEnv},
+		{mode: VarsInjectionPipe},
+		{mode: "stdin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateVarsInjection(tt.mode); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateVarsInjection(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+		}
+	}
+}
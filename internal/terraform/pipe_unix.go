@@ -0,0 +1,40 @@
+//go:build !windows
+
+package terraform
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// varsPipeFD is the descriptor of the vars pipe in Terraform: the first of cmd.ExtraFiles
+const varsPipeFD = 3
+
+// newVarsPipe creates a pipe that yields content exactly once and then EOF. The returned
+// read end must be passed to Terraform as its first extra file, which then reads it from
+// the returned /dev/fd path, so the content never touches the disk. The cleanup function
+// closes the read end and waits for the writer; call it after the command has exited.
+func newVarsPipe(content []byte) (*os.File, string, func(), error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to create vars pipe: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Fails with EPIPE once every read end is closed, if Terraform never read the content
+		if _, err := writer.Write(content); err != nil {
+			slog.Debug("Vars pipe closed before its content was read", "error", err)
+		}
+		writer.Close()
+	}()
+
+	cleanup := func() {
+		reader.Close()
+		<-done
+	}
+
+	return reader, fmt.Sprintf("/dev/fd/%d", varsPipeFD), cleanup, nil
+}
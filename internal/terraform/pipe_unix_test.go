@@ -0,0 +1,72 @@
+//go:build !windows
+
+package terraform
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestNewVarsPipe(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "small", content: []byte("a = \"b\"\n")},
+		{name: "empty", content: []byte{}},
+		{name: "larger than the pipe buffer", content: bytes.Repeat([]byte("x = 1\n"), 64*1024)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, path, cleanup, err := newVarsPipe(tt.content)
+			if err != nil {
+				t.Fatalf("newVarsPipe() error = %v", err)
+			}
+			defer cleanup()
+
+			if path != "/dev/fd/3" {
+				t.Errorf("path = %q, want /dev/fd/3", path)
+			}
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.content) {
+				t.Errorf("read %d bytes, want exactly the %d bytes of content", len(got), len(tt.content))
+			}
+		})
+	}
+}
+
+func TestNewVarsPipeInherited(t *testing.T) {
+	content := []byte("region = \"eu-west-1\"\n")
+	reader, path, cleanup, err := newVarsPipe(content)
+	if err != nil {
+		t.Fatalf("newVarsPipe() error = %v", err)
+	}
+	defer cleanup()
+
+	// The child reads the path twice: the second read must see EOF
+	cmd := exec.Command("sh", "-c", `cat "$0"; cat "$0"`, path)
+	cmd.ExtraFiles = []*os.File{reader}
+	got, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("reading %s in a child process: %v", path, err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("child read %q, want %q", got, content)
+	}
+}
+
+func TestNewVarsPipeUnread(t *testing.T) {
+	// Cleanup must not block when the command exits without reading the content
+	_, _, cleanup, err := newVarsPipe(bytes.Repeat([]byte("x"), 1<<20))
+	if err != nil {
+		t.Fatalf("newVarsPipe() error = %v", err)
+	}
+	cleanup()
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package terraform
+
+import (
+	"fmt"
+	"os"
+)
+
+// newVarsPipe is not supported on Windows, where inherited pipes cannot be opened by path.
+func newVarsPipe(content []byte) (*os.File, string, func(), error) {
+	return nil, "", nil, fmt.Errorf("vars injection %q is not supported on Windows", VarsInjectionPipe)
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// interruptProcess is not supported on Windows, so the caller falls back to killing the process.
+func interruptProcess(process *os.Process) error {
+	return fmt.Errorf("interrupting processes is not supported on windows")
+}
@@ -0,0 +1,88 @@
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// DefaultInitialBackoff is the wait before the first retry when none is configured
+	DefaultInitialBackoff = 5 * time.Second
+	// DefaultMaxBackoff caps the exponential backoff when no maximum is configured
+	DefaultMaxBackoff = time.Minute
+)
+
+// RetryPolicy decides whether a failed Terraform command is retried.
+// A command is retried only when its stderr matches one of the patterns.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Patterns       []*regexp.Regexp
+}
+
+// NewRetryPolicy creates a RetryPolicy, compiling the given patterns
+func NewRetryPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration, patterns []string) (*RetryPolicy, error) {
+	policy := &RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: initialBackoff,
+		MaxBackoff:     maxBackoff,
+	}
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry pattern %q: %w", pattern, err)
+		}
+		policy.Patterns = append(policy.Patterns, re)
+	}
+
+	return policy, nil
+}
+
+// attempts returns the total number of attempts allowed, at least one
+func (p *RetryPolicy) attempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// match returns the first pattern matching stderr, or nil
+func (p *RetryPolicy) match(stderr string) *regexp.Regexp {
+	if p == nil {
+		return nil
+	}
+	for _, re := range p.Patterns {
+		if re.MatchString(stderr) {
+			return re
+		}
+	}
+	return nil
+}
+
+// backoff returns the wait before the given retry (1 for the first retry)
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	backoff := p.InitialBackoff
+	if backoff <= 0 {
+		backoff = DefaultInitialBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// Attempt records a single execution of a Terraform command
+type Attempt struct {
+	Command  string
+	Number   int
+	Duration time.Duration
+	Err      error
+}
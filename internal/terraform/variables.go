@@ -0,0 +1,61 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// variableBlockPattern matches the header of a variable block
+var variableBlockPattern = regexp.MustCompile(`(?m)^\s*variable\s+"([^"]+)"\s*\{`)
+
+// sensitiveAttributePattern matches sensitive = true inside a variable block
+var sensitiveAttributePattern = regexp.MustCompile(`(?m)^\s*sensitive\s*=\s*true\s*(#.*|//.*)?$`)
+
+// SensitiveVariables returns the names of the variables declared with sensitive = true
+// in the .tf files of a working directory, in lexical order.
+// A missing directory declares no variables.
+func SensitiveVariables(workingDir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(workingDir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for terraform files: %w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		for _, match := range variableBlockPattern.FindAllSubmatchIndex(content, -1) {
+			body := blockBody(content[match[1]:])
+			if sensitiveAttributePattern.Match(body) {
+				names = append(names, string(content[match[2]:match[3]]))
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// blockBody returns the content up to the brace closing the block that content is inside.
+// Braces in strings and comments are not ignored, which is enough for variable blocks.
+func blockBody(content []byte) []byte {
+	depth := 1
+	for i, c := range content {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return content[:i]
+			}
+		}
+	}
+	return content
+}
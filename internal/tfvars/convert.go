@@ -0,0 +1,305 @@
+package tfvars
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// identifierPattern matches object keys that can be written without quotes
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// IsVariableName reports whether name is a valid Terraform variable name, which can be
+// written as the left-hand side of a tfvars line and in a TF_VAR_ environment variable
+func IsVariableName(name string) bool {
+	return identifierPattern.MatchString(name)
+}
+
+// FromMap converts values decoded from YAML into variables sorted by name
+func FromMap(values map[string]interface{}, source string) ([]Variable, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	variables := make([]Variable, 0, len(values))
+	for _, name := range names {
+		variable, err := FromValue(name, values[name])
+		if err != nil {
+			return nil, err
+		}
+		variable.Source = source
+		variables = append(variables, variable)
+	}
+
+	return variables, nil
+}
+
+// FromValue converts a value decoded from YAML into a variable with an HCL-encoded value
+func FromValue(name string, value interface{}) (Variable, error) {
+	encoded, err := encodeValue(value, 0)
+	if err != nil {
+		return Variable{}, fmt.Errorf("variable %s: %w", name, err)
+	}
+
+	return Variable{
+		Name:  name,
+		Value: encoded,
+		Type:  typeOf(value),
+	}, nil
+}
+
+// typeOf returns the variable type of a value decoded from YAML
+func typeOf(value interface{}) VariableType {
+	switch value.(type) {
+	case bool:
+		return BoolType
+	case int, int64, uint64, float64:
+		return NumberType
+	case []interface{}:
+		return ArrayType
+	case map[string]interface{}, map[interface{}]interface{}:
+		return ObjectType
+	default:
+		return StringType
+	}
+}
+
+// encodeValue encodes a value decoded from YAML as an HCL expression
+func encodeValue(value interface{}, depth int) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return quoteString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			encoded, err := encodeValue(item, depth+1)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, encoded)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		return encodeObject(v, depth)
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = item
+		}
+		return encodeObject(converted, depth)
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// encodeObject encodes a map as a multi-line HCL object with sorted keys
+func encodeObject(object map[string]interface{}, depth int) (string, error) {
+	if len(object) == 0 {
+		return "{}", nil
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	indent := strings.Repeat("  ", depth+1)
+	var builder strings.Builder
+	builder.WriteString("{\n")
+	for _, key := range keys {
+		encoded, err := encodeValue(object[key], depth+1)
+		if err != nil {
+			return "", err
+		}
+		if !identifierPattern.MatchString(key) {
+			key = quoteString(key)
+		}
+		builder.WriteString(fmt.Sprintf("%s%s = %s\n", indent, key, encoded))
+	}
+	builder.WriteString(strings.Repeat("  ", depth) + "}")
+
+	return builder.String(), nil
+}
+
+// quoteString quotes a string as an HCL string literal, escaping template sequences
+func quoteString(s string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			builder.WriteString(`\"`)
+		case '\\':
+			builder.WriteString(`\\`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		case '$', '%':
+			// "${" and "%{" start template sequences in HCL
+			builder.WriteByte(c)
+			if i+1 < len(s) && s[i+1] == '{' {
+				builder.WriteByte(c)
+			}
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+// UnquoteString decodes an HCL string literal, the inverse of quoteString: it resolves
+// backslash escapes and the "$${" and "%%{" template escapes. It reports false if s is
+// not a quoted literal.
+func UnquoteString(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", false
+	}
+	s = s[1 : len(s)-1]
+
+	var builder strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(s) {
+				return "", false
+			}
+			i++
+			switch s[i] {
+			case '"':
+				builder.WriteByte('"')
+			case '\\':
+				builder.WriteByte('\\')
+			case 'n':
+				builder.WriteByte('\n')
+			case 'r':
+				builder.WriteByte('\r')
+			case 't':
+				builder.WriteByte('\t')
+			case 'u', 'U':
+				digits := 4
+				if s[i] == 'U' {
+					digits = 8
+				}
+				if i+digits >= len(s) {
+					return "", false
+				}
+				code, err := strconv.ParseUint(s[i+1:i+1+digits], 16, 32)
+				if err != nil {
+					return "", false
+				}
+				builder.WriteRune(rune(code))
+				i += digits
+			default:
+				return "", false
+			}
+		case (c == '$' || c == '%') && i+2 < len(s) && s[i+1] == c && s[i+2] == '{':
+			// "$${" and "%%{" are the literal "${" and "%{"
+			builder.WriteByte(c)
+			builder.WriteByte('{')
+			i += 2
+		case c == '"':
+			return "", false
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String(), true
+}
+
+// Leaves returns the string and number values nested in an HCL-encoded value, such as the
+// fields of an object or the items of a list, with strings decoded. Object keys, booleans,
+// null and anything that is not a literal, like references and heredocs, are left out.
+func Leaves(value string) []string {
+	var leaves []string
+	for i := 0; i < len(value); {
+		c := value[i]
+		switch {
+		case c == '"':
+			end := closingQuote(value, i)
+			if end < 0 {
+				return leaves
+			}
+			literal := value[i : end+1]
+			i = end + 1
+			if isKey(value, i) {
+				continue
+			}
+			if decoded, ok := UnquoteString(literal); ok {
+				leaves = append(leaves, decoded)
+			}
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(value) && value[i+1] >= '0' && value[i+1] <= '9':
+			start := i
+			i++
+			for i < len(value) && strings.IndexByte("0123456789.eE+-", value[i]) >= 0 {
+				i++
+			}
+			if !isKey(value, i) {
+				leaves = append(leaves, value[start:i])
+			}
+		case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			// Identifiers are keys, keywords or references: skip them whole, digits included
+			for i < len(value) && isIdentifierByte(value[i]) {
+				i++
+			}
+		case c == '#' || c == '/' && i+1 < len(value) && value[i+1] == '/':
+			for i < len(value) && value[i] != '\n' {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return leaves
+}
+
+// closingQuote returns the index of the quote closing the string literal starting at start, or -1
+func closingQuote(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// isKey reports whether the token ending before i is an object key, followed by "=" or ":"
+func isKey(s string, i int) bool {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	if i >= len(s) {
+		return false
+	}
+	return s[i] == ':' || s[i] == '=' && (i+1 >= len(s) || s[i+1] != '=')
+}
+
+// isIdentifierByte reports whether c can continue an identifier
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '-' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,103 @@
+package tfvars
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnquoteString(t *testing.T) {
+	tests := []struct {
+		name    string
+		literal string
+		want    string
+		wantOK  bool
+	}{
+		{name: "plain", literal: `"abc"`, want: "abc", wantOK: true},
+		{name: "empty", literal: `""`, want: "", wantOK: true},
+		{name: "backslash escapes", literal: `"a\"b\\c\nd\re\tf"`, want: "a\"b\\c\nd\re\tf", wantOK: true},
+		{name: "template escapes", literal: `"a-$${x} %%{if}"`, want: "a-${x} %{if}", wantOK: true},
+		{name: "lone dollar and percent", literal: `"$5 or 10%"`, want: "$5 or 10%", wantOK: true},
+		{name: "unicode escapes", literal: `"café \U0001F600"`, want: "café 😀", wantOK: true},
+		{name: "unquoted", literal: `abc`, wantOK: false},
+		{name: "unknown escape", literal: `"a\qb"`, wantOK: false},
+		{name: "truncated unicode escape", literal: `"\u00"`, wantOK: false},
+		{name: "unescaped quote", literal: `"a"b"`, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := UnquoteString(tt.literal)
+			if ok != tt.wantOK {
+				t.Fatalf("UnquoteString(%s) ok = %v, want %v", tt.literal, ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Errorf("UnquoteString(%s) = %q, want %q", tt.literal, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnquoteStringRoundTrip(t *testing.T) {
+	for _, s := range []string{"", "a-${x}", "%{ for x in y }", "quote \" and \\ backslash", "line\nbreak\ttab", "$${already}"} {
+		got, ok := UnquoteString(quoteString(s))
+		if !ok || got != s {
+			t.Errorf("UnquoteString(quoteString(%q)) = %q, %v", s, got, ok)
+		}
+	}
+}
+
+func TestIsVariableName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "region", want: true},
+		{name: "_private", want: true},
+		{name: "db-password_2", want: true},
+		{name: "", want: false},
+		{name: "2fast", want: false},
+		{name: "x y", want: false},
+		{name: "a = 1\nb", want: false},
+		{name: "a.b", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsVariableName(tt.name); got != tt.want {
+			t.Errorf("IsVariableName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLeaves(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "string", value: `"hunter22"`, want: []string{"hunter22"}},
+		{name: "escaped string", value: `"a\"b-$${c}"`, want: []string{`a"b-${c}`}},
+		{name: "number", value: "-12.5e3", want: []string{"-12.5e3"}},
+		{name: "bool and null", value: "[true, null]", want: nil},
+		{name: "list", value: `["alpha", 1234, "beta"]`, want: []string{"alpha", "1234", "beta"}},
+		{
+			name:  "object keys are skipped",
+			value: "{\n  password = \"hunter22\"\n  \"user name\" = \"admin\"\n  port: 5432\n}",
+			want:  []string{"hunter22", "admin", "5432"},
+		},
+		{
+			name:  "nested object with comments",
+			value: "{\n  # credentials\n  db = {\n    token = \"s3cr3t\" // inline\n  }\n  ids = [\"id-1\"]\n}",
+			want:  []string{"s3cr3t", "id-1"},
+		},
+		{name: "reference", value: "var.other", want: nil},
+		{name: "unterminated", value: `["ok", "bro`, want: []string{"ok"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Leaves(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Leaves(%s) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
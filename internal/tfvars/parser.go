@@ -13,6 +13,10 @@ type Variable struct {
 	Name  string
 	Value string
 	Type  VariableType
+	// Source describes where the variable was defined (e.g. a vars file path)
+	Source string
+	// Sensitive marks secret values that must never be logged
+	Sensitive bool
 }
 
 type VariableType int